@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// Authenticator establishes who sent an HTTP request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*thinking.Principal, error)
+}
+
+// errUnauthenticated is returned when a request carries no usable credentials
+var errUnauthenticated = errors.New("missing or invalid credentials")
+
+// requireAuth rejects requests the authenticator does not accept and stores
+// the principal of accepted ones in the request context
+func requireAuth(auth Authenticator, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := auth.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="sequentialthinking"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(thinking.ContextWithPrincipal(r.Context(), principal)))
+	})
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+// tokenAuthenticator accepts a fixed set of bearer tokens
+type tokenAuthenticator struct {
+	// principals maps the SHA-256 of each token to its principal, so
+	// lookups do not compare secrets byte by byte
+	principals map[[sha256.Size]byte]thinking.Principal
+}
+
+// newTokenAuthenticator loads tokens from a file with one "subject token
+// [tenant]" entry per line. Blank lines and lines starting with # are ignored.
+func newTokenAuthenticator(path string) (*tokenAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening token file: %w", err)
+	}
+	defer f.Close()
+
+	a := &tokenAuthenticator{principals: make(map[[sha256.Size]byte]thinking.Principal)}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("token file line %d: expected \"subject token [tenant]\"", line)
+		}
+		p := thinking.Principal{Subject: fields[0], Method: "token"}
+		if len(fields) == 3 {
+			p.Tenant = fields[2]
+		}
+		a.principals[sha256.Sum256([]byte(fields[1]))] = p
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading token file: %w", err)
+	}
+	if len(a.principals) == 0 {
+		return nil, fmt.Errorf("token file %s contains no tokens", path)
+	}
+
+	return a, nil
+}
+
+// Authenticate accepts requests bearing one of the configured tokens
+func (a *tokenAuthenticator) Authenticate(r *http.Request) (*thinking.Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+
+	p, ok := a.principals[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, errUnauthenticated
+	}
+
+	return &p, nil
+}
+
+// jwtAuthenticator accepts HMAC-signed JWTs verified with a local key
+type jwtAuthenticator struct {
+	key    []byte
+	parser *jwt.Parser
+}
+
+// newJWTAuthenticator loads the HMAC key from keyFile. Issuer and audience
+// are enforced when non-empty.
+func newJWTAuthenticator(keyFile, issuer, audience string) (*jwtAuthenticator, error) {
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading JWT key: %w", err)
+	}
+	key = []byte(strings.TrimSpace(string(key)))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("JWT key file %s is empty", keyFile)
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+
+	return &jwtAuthenticator{key: key, parser: jwt.NewParser(opts...)}, nil
+}
+
+// Authenticate accepts requests bearing a valid, unexpired JWT with a subject
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (*thinking.Principal, error) {
+	raw, ok := bearerToken(r)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+
+	var claims jwtClaims
+	if _, err := a.parser.ParseWithClaims(raw, &claims, func(*jwt.Token) (interface{}, error) {
+		return a.key, nil
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnauthenticated, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", errUnauthenticated)
+	}
+
+	return &thinking.Principal{Subject: claims.Subject, Method: "jwt", Tenant: claims.Tenant}, nil
+}
+
+// jwtClaims are the registered claims plus an optional tenant claim
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// mtlsAuthenticator accepts requests whose client certificate was verified
+// during the TLS handshake; the subject is the certificate's common name and
+// the tenant its first organization, if any
+type mtlsAuthenticator struct{}
+
+// Authenticate accepts requests with a verified client certificate
+func (mtlsAuthenticator) Authenticate(r *http.Request) (*thinking.Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, errUnauthenticated
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, fmt.Errorf("%w: client certificate has no common name", errUnauthenticated)
+	}
+
+	p := &thinking.Principal{Subject: cert.Subject.CommonName, Method: "mtls"}
+	if len(cert.Subject.Organization) > 0 {
+		p.Tenant = cert.Subject.Organization[0]
+	}
+
+	return p, nil
+}
+
+// authConfig selects and configures the authenticator for network transports
+type authConfig struct {
+	mode        string
+	tokenFile   string
+	jwtKeyFile  string
+	jwtIssuer   string
+	jwtAudience string
+}
+
+// newAuthenticator builds the authenticator for cfg; it returns nil when
+// authentication is disabled
+func newAuthenticator(cfg authConfig) (Authenticator, error) {
+	switch cfg.mode {
+	case "none", "":
+		return nil, nil
+	case "token":
+		return newTokenAuthenticator(cfg.tokenFile)
+	case "jwt":
+		return newJWTAuthenticator(cfg.jwtKeyFile, cfg.jwtIssuer, cfg.jwtAudience)
+	case "mtls":
+		return mtlsAuthenticator{}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth mode: %s", cfg.mode)
+	}
+}
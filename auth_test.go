@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+
+	return path
+}
+
+func requestWithToken(token string) *http.Request {
+	r := httptest.NewRequest("POST", "/mcp", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return r
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	path := writeTempFile(t, "tokens", "# subject token\nalice s3cret-a\n\nbob s3cret-b\n")
+	auth, err := newTokenAuthenticator(path)
+	if err != nil {
+		t.Fatalf("newTokenAuthenticator failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		wantSubject string
+		wantErr     bool
+	}{
+		{name: "alice", token: "s3cret-a", wantSubject: "alice"},
+		{name: "bob", token: "s3cret-b", wantSubject: "bob"},
+		{name: "unknown token", token: "guess", wantErr: true},
+		{name: "missing token", token: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := auth.Authenticate(requestWithToken(tt.token))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && p.Subject != tt.wantSubject {
+				t.Errorf("Expected subject '%s', got '%s'", tt.wantSubject, p.Subject)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key := "local-hmac-key"
+	auth, err := newJWTAuthenticator(writeTempFile(t, "jwt.key", key+"\n"), "issuer", "")
+	if err != nil {
+		t.Fatalf("newJWTAuthenticator failed: %v", err)
+	}
+
+	sign := func(method jwt.SigningMethod, signingKey interface{}, claims jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(signingKey)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return token
+	}
+	valid := jwt.RegisteredClaims{
+		Subject:   "alice",
+		Issuer:    "issuer",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "someone-else"
+	noSubject := valid
+	noSubject.Subject = ""
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: sign(jwt.SigningMethodHS256, []byte(key), valid)},
+		{name: "expired", token: sign(jwt.SigningMethodHS256, []byte(key), expired), wantErr: true},
+		{name: "wrong key", token: sign(jwt.SigningMethodHS256, []byte("other"), valid), wantErr: true},
+		{name: "wrong issuer", token: sign(jwt.SigningMethodHS256, []byte(key), wrongIssuer), wantErr: true},
+		{name: "no subject", token: sign(jwt.SigningMethodHS256, []byte(key), noSubject), wantErr: true},
+		{name: "alg none", token: sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := auth.Authenticate(requestWithToken(tt.token))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && p.Subject != "alice" {
+				t.Errorf("Expected subject 'alice', got '%s'", p.Subject)
+			}
+		})
+	}
+}
+
+func TestMTLSAuthenticator(t *testing.T) {
+	r := httptest.NewRequest("POST", "/mcp", nil)
+	if _, err := (mtlsAuthenticator{}).Authenticate(r); err == nil {
+		t.Error("Expected error for plain HTTP request")
+	}
+
+	r.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "carol"}}}},
+	}
+	p, err := (mtlsAuthenticator{}).Authenticate(r)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if p.Subject != "carol" {
+		t.Errorf("Expected subject 'carol', got '%s'", p.Subject)
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	auth, err := newTokenAuthenticator(writeTempFile(t, "tokens", "alice s3cret\n"))
+	if err != nil {
+		t.Fatalf("newTokenAuthenticator failed: %v", err)
+	}
+
+	var seen string
+	handler := requireAuth(auth, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := thinking.PrincipalFromContext(r.Context())
+		seen = p.Subject
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestWithToken("wrong"))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rec.Code)
+	}
+	if rec.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected WWW-Authenticate header on 401")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestWithToken("s3cret"))
+	if rec.Code != http.StatusOK || seen != "alice" {
+		t.Errorf("Expected alice to be admitted, got status %d subject '%s'", rec.Code, seen)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultCORSHeaders are the request headers MCP browser clients send
+var defaultCORSHeaders = []string{"Authorization", "Content-Type", "Last-Event-ID", "Mcp-Protocol-Version", "Mcp-Session-Id"}
+
+// corsExposedHeaders are the response headers browser clients must be able
+// to read: the streamable HTTP session ID and the auth challenge
+const corsExposedHeaders = "Mcp-Session-Id, WWW-Authenticate"
+
+// corsConfig controls which browser origins may call the MCP endpoints
+type corsConfig struct {
+	// allowedOrigins holds "*" or origin patterns such as
+	// https://app.example.com or https://*.example.com; empty disables CORS
+	allowedOrigins   []string
+	allowedHeaders   []string
+	allowCredentials bool
+	maxAge           time.Duration
+}
+
+// newCORSConfig validates the CORS settings; origins and headers are
+// comma-separated lists
+func newCORSConfig(origins, headers string, allowCredentials bool, maxAge time.Duration) (corsConfig, error) {
+	cfg := corsConfig{
+		allowedOrigins:   splitList(origins),
+		allowedHeaders:   splitList(headers),
+		allowCredentials: allowCredentials,
+		maxAge:           maxAge,
+	}
+	if len(cfg.allowedHeaders) == 0 {
+		cfg.allowedHeaders = defaultCORSHeaders
+	}
+
+	for _, origin := range cfg.allowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if !strings.Contains(origin, "://") {
+			return corsConfig{}, fmt.Errorf("CORS origin %q must include a scheme, e.g. https://%s", origin, origin)
+		}
+		if _, err := path.Match(origin, ""); err != nil {
+			return corsConfig{}, fmt.Errorf("invalid CORS origin pattern %q: %w", origin, err)
+		}
+	}
+	if allowCredentials && slices.Contains(cfg.allowedOrigins, "*") {
+		return corsConfig{}, errors.New("CORS credentials cannot be allowed for every origin (*)")
+	}
+
+	return cfg, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// allowOrigin reports whether origin matches one of the allowed patterns
+func (c corsConfig) allowOrigin(origin string) bool {
+	for _, pattern := range c.allowedOrigins {
+		if pattern == "*" {
+			return true
+		}
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(origin)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// handler adds CORS headers to responses for allowed origins and answers
+// preflight requests itself. It must wrap requireAuth: browsers send
+// preflights without credentials.
+func (c corsConfig) handler(next http.Handler) http.Handler {
+	if len(c.allowedOrigins) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin == "" || !c.allowOrigin(origin) {
+			if preflight {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			// Without CORS headers the browser hides the response from the page
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if slices.Contains(c.allowedOrigins, "*") && !c.allowCredentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if c.allowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.allowedHeaders, ", "))
+		if c.maxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewCORSConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     string
+		credentials bool
+		wantErr     bool
+	}{
+		{name: "disabled", origins: ""},
+		{name: "exact and pattern", origins: "https://app.example.com, https://*.example.org"},
+		{name: "any", origins: "*"},
+		{name: "missing scheme", origins: "app.example.com", wantErr: true},
+		{name: "bad pattern", origins: "https://[app", wantErr: true},
+		{name: "credentials for any origin", origins: "*", credentials: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newCORSConfig(tt.origins, "", tt.credentials, time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newCORSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCORSHandler(t *testing.T) {
+	cfg, err := newCORSConfig("https://app.example.com,https://*.example.org", "", true, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("newCORSConfig failed: %v", err)
+	}
+	reached := false
+	handler := cfg.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+
+	serve := func(method, origin string, preflight bool) *httptest.ResponseRecorder {
+		reached = false
+		r := httptest.NewRequest(method, "/mcp", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if preflight {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := serve(http.MethodOptions, "https://app.example.com", true)
+	if rec.Code != http.StatusNoContent || reached {
+		t.Errorf("Expected preflight to be answered with 204, got %d (reached next: %v)", rec.Code, reached)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST, DELETE, OPTIONS",
+		"Access-Control-Allow-Headers":     "Authorization, Content-Type, Last-Event-ID, Mcp-Protocol-Version, Mcp-Session-Id",
+		"Access-Control-Max-Age":           "600",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = '%s', want '%s'", header, got, want)
+		}
+	}
+
+	if rec := serve(http.MethodOptions, "https://evil.example.com", true); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a preflight from a disallowed origin, got %d", rec.Code)
+	}
+
+	rec = serve(http.MethodPost, "https://tools.example.org", false)
+	if !reached || rec.Header().Get("Access-Control-Allow-Origin") != "https://tools.example.org" {
+		t.Errorf("Expected a pattern-matched origin to be allowed, got headers %v", rec.Header())
+	}
+	if rec.Header().Get("Access-Control-Expose-Headers") != corsExposedHeaders {
+		t.Errorf("Expected exposed headers on actual requests, got '%s'", rec.Header().Get("Access-Control-Expose-Headers"))
+	}
+
+	rec = serve(http.MethodPost, "https://evil.example.com", false)
+	if !reached || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers for a disallowed origin, got %v", rec.Header())
+	}
+}
+
+func TestCORSPreflightBypassesAuth(t *testing.T) {
+	auth, err := newTokenAuthenticator(writeTempFile(t, "tokens", "alice s3cret\n"))
+	if err != nil {
+		t.Fatalf("newTokenAuthenticator failed: %v", err)
+	}
+	cors, err := newCORSConfig("*", "", false, 0)
+	if err != nil {
+		t.Fatalf("newCORSConfig failed: %v", err)
+	}
+
+	mcpServer, _ := newTestMCPServer()
+	mux := http.NewServeMux()
+	shutdown := mountTransports(mux, mcpServer, &http.Server{}, httpConfig{auth: auth, cors: cors}, []string{"sse", "http"})
+	defer shutdown(t.Context())
+
+	for _, path := range []string{"/mcp", "/message", "/sse"} {
+		r := httptest.NewRequest(http.MethodOptions, path, nil)
+		r.Header.Set("Origin", "https://app.example.com")
+		r.Header.Set("Access-Control-Request-Method", "POST")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("Preflight on %s: got %d with headers %v", path, rec.Code, rec.Header())
+		}
+
+		// Rejected requests still carry CORS headers so the page can read the 401
+		r = httptest.NewRequest(http.MethodPost, path, nil)
+		r.Header.Set("Origin", "https://app.example.com")
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("Unauthenticated POST on %s: got %d with headers %v", path, rec.Code, rec.Header())
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// dashboardHeartbeat keeps idle event streams open through proxies
+const dashboardHeartbeat = 15 * time.Second
+
+// SessionSummary describes a session without its thoughts
+type SessionSummary struct {
+	ID        string    `json:"id"`
+	Thoughts  int       `json:"thoughts"`
+	Branches  int       `json:"branches"`
+	Revisions int       `json:"revisions"`
+	Complete  bool      `json:"complete"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// summarizeSession condenses a session for listings
+func summarizeSession(id string, history *thinking.ThoughtHistory) SessionSummary {
+	summary := SessionSummary{
+		ID:        id,
+		Thoughts:  len(history.Thoughts),
+		Branches:  len(history.Branches),
+		CreatedAt: history.CreatedAt,
+	}
+	for _, thought := range history.Thoughts {
+		if thought.IsRevision {
+			summary.Revisions++
+		}
+	}
+	if n := len(history.Thoughts); n > 0 {
+		summary.Complete = !history.Thoughts[n-1].NextThoughtNeeded
+	}
+
+	return summary
+}
+
+// listSessions summarizes the sessions in store the caller in ctx owns,
+// newest first
+func listSessions(ctx context.Context, store thinking.SessionStore) []SessionSummary {
+	sessions := []SessionSummary{}
+	for _, id := range store.List() {
+		if !thinking.OwnsSession(ctx, id) {
+			continue
+		}
+		if history, ok := store.Get(id); ok {
+			sessions = append(sessions, summarizeSession(id, history))
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+
+	return sessions
+}
+
+// dashboard serves the web UI and the session data it renders. Every
+// request only sees the sessions of the caller's tenant, and authenticated
+// callers only their own.
+type dashboard struct {
+	thinker *thinking.SequentialThinkingServer
+	cfg     httpConfig
+
+	// done ends open event streams, which http.Server.Shutdown does not
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newDashboard(thinker *thinking.SequentialThinkingServer, cfg httpConfig) *dashboard {
+	return &dashboard{thinker: thinker, cfg: cfg, done: make(chan struct{})}
+}
+
+// register mounts the UI and its data endpoints under /dashboard/
+func (d *dashboard) register(mux *http.ServeMux) {
+	assets, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+
+	mux.HandleFunc("GET /dashboard", func(w http.ResponseWriter, r *http.Request) {
+		// A relative Location keeps any -base-path prefix intact
+		w.Header().Set("Location", "dashboard/")
+		w.WriteHeader(http.StatusMovedPermanently)
+	})
+	mux.Handle("GET /dashboard/", http.StripPrefix("/dashboard/", http.FileServerFS(assets)))
+	mux.Handle("GET /dashboard/sessions", requireAuth(d.cfg.auth, http.HandlerFunc(d.handleSessions)))
+	mux.Handle("GET /dashboard/sessions/{id...}", requireAuth(d.cfg.auth, http.HandlerFunc(d.handleSession)))
+	mux.Handle("GET /dashboard/events", requireAuth(d.cfg.auth, http.HandlerFunc(d.handleEvents)))
+}
+
+// close ends all open event streams
+func (d *dashboard) close() {
+	d.closeOnce.Do(func() { close(d.done) })
+}
+
+// handleSessions lists the caller's sessions, newest first
+func (d *dashboard) handleSessions(w http.ResponseWriter, r *http.Request) {
+	store, err := d.cfg.storeOf(d.thinker, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listSessions(r.Context(), store))
+}
+
+// handleSession returns one session with all its thoughts
+func (d *dashboard) handleSession(w http.ResponseWriter, r *http.Request) {
+	store, err := d.cfg.storeOf(d.thinker, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	if !thinking.OwnsSession(r.Context(), id) {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	history, ok := store.Get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// handleEvents streams the events of the caller's sessions as Server-Sent Events
+func (d *dashboard) handleEvents(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := d.cfg.tenantOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := d.thinker.Subscribe(64)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(dashboardHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			if event.Tenant != tenantID || !thinking.OwnsSession(r.Context(), event.SessionID) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-d.done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,147 @@
+// Live view of the sessions of the caller's tenant. All URLs are relative so
+// the dashboard works under any -base-path.
+"use strict";
+
+const sessionsList = document.getElementById("sessions");
+const tree = document.getElementById("tree");
+const title = document.getElementById("session-title");
+const status = document.getElementById("status");
+
+let selected = null;
+let seenThoughts = 0;
+
+async function fetchJSON(url) {
+  const response = await fetch(url, { credentials: "same-origin" });
+  if (!response.ok) {
+    throw new Error(`${url}: ${response.status}`);
+  }
+  return response.json();
+}
+
+async function loadSessions() {
+  const sessions = await fetchJSON("sessions");
+  sessionsList.replaceChildren(...sessions.map((session) => {
+    const item = document.createElement("li");
+    item.className = session.id === selected ? "selected" : "";
+    item.onclick = () => selectSession(session.id);
+
+    const name = document.createElement("div");
+    name.textContent = session.id;
+    const meta = document.createElement("div");
+    meta.className = "meta";
+    meta.textContent = `${session.thoughts} thoughts · ${session.branches} branches · ` +
+      `${session.revisions} revisions · ${session.complete ? "complete" : "thinking…"}`;
+
+    item.append(name, meta);
+    return item;
+  }));
+}
+
+async function selectSession(id) {
+  if (selected !== id) {
+    seenThoughts = 0;
+  }
+  selected = id;
+  title.textContent = id;
+  await Promise.all([loadSessions(), loadSession()]);
+}
+
+async function loadSession() {
+  if (selected === null) {
+    return;
+  }
+  const history = await fetchJSON("sessions/" + encodeURIComponent(selected));
+  tree.replaceChildren(renderTree(history.thoughts));
+  seenThoughts = history.thoughts.length;
+}
+
+// renderTree nests each branch under the thought it branched from; the
+// main line is every thought without a branch ID
+function renderTree(thoughts) {
+  const branches = new Map();
+  const main = [];
+  thoughts.forEach((thought, index) => {
+    const entry = { thought, index };
+    if (!thought.branchId) {
+      main.push(entry);
+      return;
+    }
+    const key = thought.branchId;
+    if (!branches.has(key)) {
+      branches.set(key, { from: thought.branchFromThought, entries: [] });
+    }
+    branches.get(key).entries.push(entry);
+  });
+
+  const renderList = (entries) => {
+    const list = document.createElement("ul");
+    list.className = "thoughts";
+    for (const entry of entries) {
+      list.append(renderThought(entry));
+      for (const [id, branch] of branches) {
+        if (branch.from === entry.thought.thoughtNumber && !entry.thought.branchId) {
+          const item = document.createElement("li");
+          const label = document.createElement("span");
+          label.className = "label";
+          label.textContent = `⑂ branch ${id}`;
+          item.append(label, renderList(branch.entries));
+          list.append(item);
+          branches.delete(id);
+        }
+      }
+    }
+    return list;
+  };
+
+  const root = renderList(main);
+  // Branches whose origin is not on the main line still get shown
+  for (const [id, branch] of branches) {
+    const label = document.createElement("span");
+    label.className = "label";
+    label.textContent = `⑂ branch ${id} from thought ${branch.from}`;
+    root.append(label, renderList(branch.entries));
+  }
+  return root;
+}
+
+function renderThought({ thought, index }) {
+  const item = document.createElement("li");
+  item.className = "thought";
+  if (thought.isRevision) item.classList.add("revision");
+  if (thought.branchId) item.classList.add("branch");
+  if (!thought.nextThoughtNeeded) item.classList.add("final");
+  if (index >= seenThoughts && seenThoughts > 0) item.classList.add("new");
+
+  const label = document.createElement("span");
+  label.className = "label";
+  let text = `Thought ${thought.thoughtNumber}/${thought.totalThoughts}`;
+  if (thought.isRevision) text += ` · revises ${thought.revisesThought}`;
+  if (thought.branchId) text += ` · branch ${thought.branchId}`;
+  if (thought.needsMoreThoughts) text += " · needs more thoughts";
+  label.textContent = text;
+
+  item.append(label, document.createTextNode(thought.thought));
+  return item;
+}
+
+function connect() {
+  const events = new EventSource("events");
+  events.onopen = () => {
+    status.textContent = "live";
+    status.className = "status online";
+  };
+  events.onerror = () => {
+    status.textContent = "reconnecting…";
+    status.className = "status offline";
+  };
+  events.addEventListener("thought.appended", (message) => {
+    const event = JSON.parse(message.data);
+    loadSessions();
+    if (event.sessionId === selected) {
+      loadSession();
+    }
+  });
+}
+
+loadSessions().catch((error) => console.error(error));
+connect();
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// startDashboard serves the dashboard of thinker, with tenants taken from X-Tenant-ID
+func startDashboard(t *testing.T, thinker *thinking.SequentialThinkingServer) (*httptest.Server, *http.Server) {
+	t.Helper()
+
+	cfg := httpConfig{dashboard: true, tenantHeader: "X-Tenant-ID"}
+	httpServer := &http.Server{}
+	mux := http.NewServeMux()
+	cfg.registerCommonHandlers(mux, httpServer, thinker)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	return ts, httpServer
+}
+
+func getAs(t *testing.T, url, tenant string) *http.Response {
+	t.Helper()
+
+	r, _ := http.NewRequest("GET", url, nil)
+	if tenant != "" {
+		r.Header.Set("X-Tenant-ID", tenant)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+
+	return resp
+}
+
+func TestDashboardServesAssets(t *testing.T) {
+	ts, _ := startDashboard(t, thinking.NewSequentialThinkingServer())
+
+	for _, path := range []string{"/dashboard/", "/dashboard/app.js", "/dashboard/style.css"} {
+		resp := getAs(t, ts.URL+path, "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(ts.URL + "/dashboard")
+	if err != nil {
+		t.Fatalf("GET /dashboard failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "dashboard/" {
+		t.Errorf("Expected a relative redirect to dashboard/, got %d %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func TestDashboardSessions(t *testing.T) {
+	thinker := thinking.NewSequentialThinkingServer()
+	alice := thinking.ContextWithPrincipal(context.Background(), &thinking.Principal{Subject: "alice", Tenant: "acme"})
+	for n, next := range []bool{true, false} {
+		if _, err := thinker.CallTool(alice, thoughtCall(n+1, next)); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+	}
+	ts, _ := startDashboard(t, thinker)
+
+	resp := getAs(t, ts.URL+"/dashboard/sessions", "acme")
+	var sessions []SessionSummary
+	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+		t.Fatalf("Decoding sessions failed: %v", err)
+	}
+	resp.Body.Close()
+	if len(sessions) != 1 || sessions[0].Thoughts != 2 || !sessions[0].Complete {
+		t.Fatalf("Unexpected sessions: %+v", sessions)
+	}
+
+	// Session IDs contain the subject and a slash
+	resp = getAs(t, ts.URL+"/dashboard/sessions/"+url.PathEscape(sessions[0].ID), "acme")
+	var history thinking.ThoughtHistory
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatalf("Decoding session failed: %v", err)
+	}
+	resp.Body.Close()
+	if len(history.Thoughts) != 2 {
+		t.Errorf("Expected 2 thoughts, got %d", len(history.Thoughts))
+	}
+
+	// Other tenants see neither the listing nor the session
+	resp = getAs(t, ts.URL+"/dashboard/sessions", "globex")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.TrimSpace(string(body)) != "[]" {
+		t.Errorf("Expected no sessions for another tenant, got %s", body)
+	}
+	resp = getAs(t, ts.URL+"/dashboard/sessions/"+url.PathEscape(sessions[0].ID), "globex")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for another tenant's session, got %d", resp.StatusCode)
+	}
+}
+
+func TestDashboardHidesOtherSubjectsSessions(t *testing.T) {
+	thinker := thinking.NewSequentialThinkingServer()
+	alice := thinking.ContextWithPrincipal(context.Background(), &thinking.Principal{Subject: "alice", Tenant: "acme"})
+	if _, err := thinker.CallTool(alice, thoughtCall(1, true)); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	auth, err := newTokenAuthenticator(writeTempFile(t, "tokens", "alice alice-token acme\nbob bob-token acme\n"))
+	if err != nil {
+		t.Fatalf("newTokenAuthenticator failed: %v", err)
+	}
+	cfg := httpConfig{dashboard: true, auth: auth}
+	mux := http.NewServeMux()
+	cfg.registerCommonHandlers(mux, &http.Server{}, thinker)
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		return rec
+	}
+
+	var sessions []SessionSummary
+	if err := json.NewDecoder(get("/dashboard/sessions", "alice-token").Body).Decode(&sessions); err != nil {
+		t.Fatalf("Decoding sessions failed: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("Expected alice to see the session, got %+v", sessions)
+	}
+
+	// bob shares the tenant but not the session
+	if body := strings.TrimSpace(get("/dashboard/sessions", "bob-token").Body.String()); body != "[]" {
+		t.Errorf("Expected bob to see no sessions, got %s", body)
+	}
+	if rec := get("/dashboard/sessions/"+url.PathEscape(sessions[0].ID), "bob-token"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another subject's session, got %d", rec.Code)
+	}
+}
+
+func TestDashboardEventStream(t *testing.T) {
+	thinker := thinking.NewSequentialThinkingServer()
+	ts, httpServer := startDashboard(t, thinker)
+
+	resp := getAs(t, ts.URL+"/dashboard/events", "acme")
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %s", ct)
+	}
+
+	// Events of other tenants must not reach the stream
+	globex := thinking.ContextWithPrincipal(context.Background(), &thinking.Principal{Subject: "bob", Tenant: "globex"})
+	acme := thinking.ContextWithPrincipal(context.Background(), &thinking.Principal{Subject: "alice", Tenant: "acme"})
+	for _, ctx := range []context.Context{globex, acme} {
+		if _, err := thinker.CallTool(ctx, thoughtCall(1, true)); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	var types []string
+	timeout := time.After(5 * time.Second)
+	for len(types) < 2 {
+		select {
+		case line := <-lines:
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var event thinking.SessionEvent
+				if err := json.Unmarshal([]byte(data), &event); err != nil {
+					t.Fatalf("Decoding event failed: %v", err)
+				}
+				if event.Tenant != "acme" {
+					t.Errorf("Received an event of tenant %s", event.Tenant)
+				}
+				types = append(types, event.Type)
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for events, got %v", types)
+		}
+	}
+	if types[0] != thinking.EventSessionCreated || types[1] != thinking.EventThoughtAppended {
+		t.Errorf("Unexpected event sequence %v", types)
+	}
+
+	// Shutting the server down ends open streams
+	httpServer.Shutdown(context.Background())
+	for {
+		select {
+		case _, ok := <-lines:
+			if !ok {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Event stream stayed open after shutdown")
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// e2eSession is a branching session run over every transport
+var e2eSession = []map[string]interface{}{
+	{"thought": "Outline the migration", "nextThoughtNeeded": true, "thoughtNumber": 1, "totalThoughts": 4},
+	{"thought": "Copy the data table by table", "nextThoughtNeeded": true, "thoughtNumber": 2, "totalThoughts": 4},
+	{"thought": "Alternatively, replicate and cut over", "nextThoughtNeeded": true, "thoughtNumber": 3, "totalThoughts": 4, "branchFromThought": 1, "branchId": "replicate"},
+	{"thought": "Table copies need a write freeze", "nextThoughtNeeded": true, "thoughtNumber": 3, "totalThoughts": 4, "isRevision": true, "revisesThought": 2},
+	{"thought": "", "nextThoughtNeeded": true, "thoughtNumber": 0, "totalThoughts": 4},
+	{"thought": "Replicate, then cut over in a short window", "nextThoughtNeeded": false, "thoughtNumber": 4, "totalThoughts": 4, "branchId": "replicate", "branchFromThought": 1},
+}
+
+// e2eTransports start mcpServer behind one transport and return a connected,
+// uninitialized client
+var e2eTransports = map[string]func(t *testing.T, mcpServer *server.MCPServer) *client.Client{
+	"stdio": startStdioClient,
+	"sse": func(t *testing.T, mcpServer *server.MCPServer) *client.Client {
+		ts := startTransport(t, mcpServer, "sse")
+		c, err := client.NewSSEMCPClient(ts.URL + "/sse")
+		if err != nil {
+			t.Fatalf("NewSSEMCPClient failed: %v", err)
+		}
+		if err := c.Start(t.Context()); err != nil {
+			t.Fatalf("Starting SSE client failed: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	},
+	"http": func(t *testing.T, mcpServer *server.MCPServer) *client.Client {
+		ts := startTransport(t, mcpServer, "http")
+		c, err := client.NewStreamableHttpClient(ts.URL + "/mcp")
+		if err != nil {
+			t.Fatalf("NewStreamableHttpClient failed: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	},
+}
+
+// startStdioClient serves mcpServer through runStdio over in-process pipes
+func startStdioClient(t *testing.T, mcpServer *server.MCPServer) *client.Client {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- runStdio(context.Background(), mcpServer, serverIn, serverOut)
+	}()
+
+	c := client.NewClient(transport.NewIO(clientIn, clientOut, io.NopCloser(strings.NewReader(""))))
+	if err := c.Start(t.Context()); err != nil {
+		t.Fatalf("Starting stdio client failed: %v", err)
+	}
+	t.Cleanup(func() {
+		// Closing the client's output is EOF on the server's stdin
+		c.Close()
+		serverOut.Close()
+		select {
+		case err := <-served:
+			if err != nil {
+				t.Errorf("runStdio failed: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("runStdio did not return after stdin closed")
+		}
+	})
+
+	return c
+}
+
+// startTransport serves one network transport the way runNetwork mounts it
+func startTransport(t *testing.T, mcpServer *server.MCPServer, name string) *httptest.Server {
+	mux := http.NewServeMux()
+	shutdown := mountTransports(mux, mcpServer, &http.Server{}, httpConfig{}, []string{name})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		_ = shutdown(context.Background())
+		ts.Close()
+	})
+
+	return ts
+}
+
+// checkGolden compares got with testdata/e2e/name, rewriting it with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "e2e", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Reading golden file: %v", err)
+	}
+	if diff := diffLines(string(want), got); diff != "" {
+		t.Errorf("Responses differ from %s (run go test -update to accept):\n%s", path, diff)
+	}
+}
+
+func TestEndToEnd(t *testing.T) {
+	for name, start := range e2eTransports {
+		t.Run(name, func(t *testing.T) {
+			// A fixed clock keeps every call in one session however long the run takes
+			mcpServer, thinker := newTestMCPServer(
+				thinking.WithLogger(slog.New(slog.DiscardHandler)),
+				thinking.WithClock(func() time.Time { return replayEpoch }),
+			)
+			c := start(t, mcpServer)
+
+			ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+			defer cancel()
+
+			if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+
+			tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+			if err != nil {
+				t.Fatalf("ListTools failed: %v", err)
+			}
+			schema, err := json.MarshalIndent(tools.Tools, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "tools.golden", string(schema)+"\n")
+
+			var transcript strings.Builder
+			for i, args := range e2eSession {
+				request := mcp.CallToolRequest{}
+				request.Params.Name = "sequentialthinking"
+				request.Params.Arguments = args
+				result, err := c.CallTool(ctx, request)
+				switch {
+				case err != nil:
+					fmt.Fprintf(&transcript, "=== %d (error)\n%v\n", i+1, err)
+				case result.IsError:
+					fmt.Fprintf(&transcript, "=== %d (tool error)\n%s\n", i+1, resultText(result))
+				default:
+					meta, _ := json.Marshal(result.Meta)
+					fmt.Fprintf(&transcript, "=== %d\n%s\n_meta: %s\n", i+1, resultText(result), meta)
+				}
+			}
+			checkGolden(t, "session.golden", transcript.String())
+
+			store := defaultStore(t, thinker)
+			if ids := store.List(); len(ids) != 1 {
+				t.Fatalf("Expected one session, got %v", ids)
+			}
+			history, _ := store.Get(store.List()[0])
+			if len(history.Thoughts) != 5 || len(history.Branches["replicate"]) != 2 {
+				t.Errorf("Unexpected session state: %+v", history)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// Build information, set at link time:
+//
+//	go build -ldflags "-X main.version=0.3.0 -X main.commit=$(git rev-parse HEAD)"
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// VersionInfo is the payload served by /version
+type VersionInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+}
+
+// buildVersion returns the build version, falling back to the VCS revision
+// recorded by the Go toolchain when the commit was not set at link time
+func buildVersion() VersionInfo {
+	info := VersionInfo{Version: version, Commit: commit}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				if setting.Key == "vcs.revision" {
+					info.Commit = setting.Value
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+// registerHealthHandlers mounts the liveness, readiness and version endpoints
+func registerHealthHandlers(mux *http.ServeMux, thinker *thinking.SequentialThinkingServer) {
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		handleReadyz(w, r, thinker)
+	})
+	mux.HandleFunc("/version", handleVersion)
+}
+
+// handleHealthz reports that the process is alive
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// handleReadyz reports whether the server and its session store can take traffic
+func handleReadyz(w http.ResponseWriter, r *http.Request, thinker *thinking.SequentialThinkingServer) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if err := thinker.Ready(); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("not ready: " + err.Error() + "\n"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// handleVersion reports the build version and git commit
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(buildVersion())
+}
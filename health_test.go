@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+func newHealthTestServer(t *testing.T, thinker *thinking.SequentialThinkingServer) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	registerHealthHandlers(mux, thinker)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func TestHealthz(t *testing.T) {
+	ts := newHealthTestServer(t, thinking.NewSequentialThinkingServer())
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	thinker := thinking.NewSequentialThinkingServer()
+	ts := newHealthTestServer(t, thinker)
+
+	resp, err := http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("GET /readyz failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 before shutdown, got %d", resp.StatusCode)
+	}
+
+	if err := thinker.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	resp, err = http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("GET /readyz failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 after shutdown, got %d", resp.StatusCode)
+	}
+}
+
+func TestReadyzFailsWhileDraining(t *testing.T) {
+	mcpServer, thinker := newTestMCPServer()
+	ts := newHealthTestServer(t, thinker)
+
+	thinker.Drain()
+
+	resp, err := http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("GET /readyz failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while draining, got %d", resp.StatusCode)
+	}
+
+	c, err := client.NewInProcessClient(mcpServer)
+	if err != nil {
+		t.Fatalf("NewInProcessClient failed: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.Initialize(context.Background(), mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	result, err := c.CallTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{
+		Name:      thinking.ToolName,
+		Arguments: map[string]any{"thought": "Still served", "nextThoughtNeeded": false, "thoughtNumber": 1, "totalThoughts": 1},
+	}})
+	if err != nil || result.IsError {
+		t.Errorf("Expected tool calls to be served while draining, got %v %v", result, err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	ts := newHealthTestServer(t, thinking.NewSequentialThinkingServer())
+
+	resp, err := http.Get(ts.URL + "/version")
+	if err != nil {
+		t.Fatalf("GET /version failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var info VersionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("Failed to decode version: %v", err)
+	}
+
+	if info.Version != version {
+		t.Errorf("Expected version '%s', got '%s'", version, info.Version)
+	}
+	if info.Commit == "" {
+		t.Error("Commit should not be empty")
+	}
+}
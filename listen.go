@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+)
+
+// listenConfig describes where the network transports accept connections
+type listenConfig struct {
+	// network is "tcp" or "unix"
+	network string
+	// address is host:port for tcp and a file path for unix
+	address string
+	// socketMode is applied to unix sockets after they are created
+	socketMode os.FileMode
+	// socketGroup, if set, becomes the group owner of unix sockets
+	socketGroup string
+}
+
+// String formats the listener the way -listen accepts it
+func (c listenConfig) String() string {
+	if c.network == "unix" {
+		return "unix://" + c.address
+	}
+
+	return "tcp://" + c.address
+}
+
+// parseListenAddress parses a -listen value: unix:///path/to.sock or tcp://host:port
+func parseListenAddress(value string) (network, address string, err error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid listen address %q: %w", value, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		// unix:///run/x.sock has an empty host; unix://run/x.sock is a
+		// relative path that url.Parse splits into host and path
+		address = u.Host + u.Path
+		if address == "" {
+			return "", "", fmt.Errorf("listen address %q has no socket path", value)
+		}
+		return "unix", address, nil
+	case "tcp":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("listen address %q has no host:port", value)
+		}
+		return "tcp", u.Host, nil
+	default:
+		return "", "", fmt.Errorf("listen address %q: scheme must be unix:// or tcp://", value)
+	}
+}
+
+// listen opens the listener described by c
+func (c listenConfig) listen() (net.Listener, error) {
+	if c.network != "unix" {
+		return net.Listen("tcp", c.address)
+	}
+
+	if err := removeStaleSocket(c.address); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", c.address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.applySocketPermissions(); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	// The socket file is removed again when the listener is closed
+	return ln, nil
+}
+
+// applySocketPermissions sets the mode and group of the socket file
+func (c listenConfig) applySocketPermissions() error {
+	if c.socketMode != 0 {
+		if err := os.Chmod(c.address, c.socketMode); err != nil {
+			return fmt.Errorf("setting socket mode: %w", err)
+		}
+	}
+	if c.socketGroup == "" {
+		return nil
+	}
+
+	gid, err := strconv.Atoi(c.socketGroup)
+	if err != nil {
+		group, lookupErr := user.LookupGroup(c.socketGroup)
+		if lookupErr != nil {
+			return fmt.Errorf("looking up socket group: %w", lookupErr)
+		}
+		if gid, err = strconv.Atoi(group.Gid); err != nil {
+			return fmt.Errorf("socket group %s has non-numeric gid %q", c.socketGroup, group.Gid)
+		}
+	}
+	if err := os.Chown(c.address, -1, gid); err != nil {
+		return fmt.Errorf("setting socket group: %w", err)
+	}
+
+	return nil
+}
+
+// removeStaleSocket deletes a socket file left behind by a server that did
+// not shut down cleanly. A socket some process still accepts on, or any
+// file that is not a socket, is left alone and reported as an error.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("removing stale socket: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+func TestParseListenAddress(t *testing.T) {
+	tests := []struct {
+		value       string
+		wantNetwork string
+		wantAddress string
+		wantErr     bool
+	}{
+		{value: "unix:///run/seqthink.sock", wantNetwork: "unix", wantAddress: "/run/seqthink.sock"},
+		{value: "unix://seqthink.sock", wantNetwork: "unix", wantAddress: "seqthink.sock"},
+		{value: "tcp://127.0.0.1:8080", wantNetwork: "tcp", wantAddress: "127.0.0.1:8080"},
+		{value: "tcp://:8080", wantNetwork: "tcp", wantAddress: ":8080"},
+		{value: "unix://", wantErr: true},
+		{value: "udp://:53", wantErr: true},
+		{value: "/run/seqthink.sock", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			network, address, err := parseListenAddress(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseListenAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if network != tt.wantNetwork || address != tt.wantAddress {
+				t.Errorf("parseListenAddress() = %s %s, want %s %s", network, address, tt.wantNetwork, tt.wantAddress)
+			}
+		})
+	}
+}
+
+func TestUnixListenerPermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seqthink.sock")
+	ln, err := listenConfig{network: "unix", address: path, socketMode: 0o600}.listen()
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Socket file missing: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected socket mode 0600, got %v", info.Mode().Perm())
+	}
+
+	ln.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the socket file to be removed on close, got %v", err)
+	}
+}
+
+func TestUnixListenerStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seqthink.sock")
+
+	// Simulate a crashed server that left its socket file behind
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("net.Listen failed: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listenConfig{network: "unix", address: path}.listen()
+	if err != nil {
+		t.Fatalf("Expected the stale socket to be replaced, got %v", err)
+	}
+
+	// A socket that is still accepting must not be taken over
+	if _, err := (listenConfig{network: "unix", address: path}).listen(); err == nil {
+		t.Error("Expected an error for a socket in use")
+	}
+	ln.Close()
+
+	regular := filepath.Join(t.TempDir(), "not-a-socket")
+	if err := os.WriteFile(regular, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := (listenConfig{network: "unix", address: regular}).listen(); err == nil {
+		t.Error("Expected an error instead of deleting a regular file")
+	}
+}
+
+func TestServeOverUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seqthink.sock")
+	cfg := httpConfig{
+		listen:   listenConfig{network: "unix", address: path},
+		deadline: newShutdownDeadline(time.Second),
+	}
+	// Each client may make one call; clients on the socket must not share it
+	mcpServer, thinker := newTestMCPServer(thinking.WithRateLimit(thinking.RateLimitConfig{Rate: 0.001, Burst: 1}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runNetwork(ctx, mcpServer, thinker, cfg, []string{"http"})
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+
+	var resp *http.Response
+	var err error
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if resp, err = client.Get("http://unix/healthz"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("GET /healthz over the socket failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	for i := 1; i <= 2; i++ {
+		c, err := mcpclient.NewStreamableHttpClient("http://unix/mcp", transport.WithHTTPBasicClient(client))
+		if err != nil {
+			t.Fatalf("Creating client %d failed: %v", i, err)
+		}
+		defer c.Close()
+		if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+			t.Fatalf("Initialize of client %d failed: %v", i, err)
+		}
+		result, err := c.CallTool(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{
+			Name:      thinking.ToolName,
+			Arguments: map[string]any{"thought": "Over the socket", "nextThoughtNeeded": false, "thoughtNumber": 1, "totalThoughts": 1},
+		}})
+		if err != nil || result.IsError {
+			t.Errorf("Call of client %d was refused: %v %v", i, result, err)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("runNetwork returned %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the socket file to be removed on shutdown, got %v", err)
+	}
+}
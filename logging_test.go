@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		wantErr bool
+	}{
+		{name: "text info", level: "info", format: "text"},
+		{name: "json debug", level: "debug", format: "json"},
+		{name: "upper case", level: "WARN", format: "JSON"},
+		{name: "invalid level", level: "verbose", format: "text", wantErr: true},
+		{name: "invalid format", level: "info", format: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newLogger(&bytes.Buffer{}, tt.level, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newLogger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
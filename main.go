@@ -1,382 +1,261 @@
+// Command sequentialthinking-server serves the sequentialthinking tool of
+// package thinking over stdio, SSE, streamable HTTP or WebSocket.
 package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"flag"
 	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
-)
-
-// ThoughtRequest represents the input parameters for the sequential thinking tool
-type ThoughtRequest struct {
-	Thought           string `json:"thought"`
-	NextThoughtNeeded bool   `json:"nextThoughtNeeded"`
-	ThoughtNumber     int    `json:"thoughtNumber"`
-	TotalThoughts     int    `json:"totalThoughts"`
-	IsRevision        bool   `json:"isRevision,omitempty"`
-	RevisesThought    int    `json:"revisesThought,omitempty"`
-	BranchFromThought int    `json:"branchFromThought,omitempty"`
-	BranchID          string `json:"branchId,omitempty"`
-	NeedsMoreThoughts bool   `json:"needsMoreThoughts,omitempty"`
-}
 
-// ThoughtHistory stores the chain of thoughts
-type ThoughtHistory struct {
-	Thoughts  []ThoughtRequest `json:"thoughts"`
-	Branches  map[string][]int `json:"branches,omitempty"`
-	CreatedAt time.Time        `json:"created_at"`
-}
+	"github.com/ad/sequentialthinking/thinking"
+)
 
-// SequentialThinkingServer implements the MCP server for sequential thinking
-type SequentialThinkingServer struct {
-	history map[string]*ThoughtHistory
+// newMCPServer creates the MCP server the sequentialthinking tool is registered on
+func newMCPServer() *server.MCPServer {
+	return server.NewMCPServer(
+		"sequentialthinking",
+		version,
+		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, false),
+		server.WithLogging(),
+	)
 }
 
-// NewSequentialThinkingServer creates a new sequential thinking server
-func NewSequentialThinkingServer() *SequentialThinkingServer {
-	return &SequentialThinkingServer{
-		history: make(map[string]*ThoughtHistory),
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:], os.Stdout, os.Stderr))
 	}
-}
 
-// ListTools returns the available tools
-func (s *SequentialThinkingServer) ListTools(ctx context.Context) ([]mcp.Tool, error) {
-	return []mcp.Tool{
-		{
-			Name:        "sequentialthinking",
-			Description: "A detailed tool for dynamic and reflective problem-solving through thoughts.\nThis tool helps analyze problems through a flexible thinking process that can adapt and evolve.\nEach thought can build on, question, or revise previous insights as understanding deepens.",
-			InputSchema: mcp.ToolInputSchema{
-				Type: "object",
-				Properties: map[string]interface{}{
-					"thought": map[string]interface{}{
-						"type":        "string",
-						"description": "Your current thinking step",
-					},
-					"nextThoughtNeeded": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Whether another thought step is needed",
-					},
-					"thoughtNumber": map[string]interface{}{
-						"type":        "integer",
-						"minimum":     1,
-						"description": "Current thought number",
-					},
-					"totalThoughts": map[string]interface{}{
-						"type":        "integer",
-						"minimum":     1,
-						"description": "Estimated total thoughts needed",
-					},
-					"isRevision": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Whether this revises previous thinking",
-					},
-					"revisesThought": map[string]interface{}{
-						"type":        "integer",
-						"minimum":     1,
-						"description": "Which thought is being reconsidered",
-					},
-					"branchFromThought": map[string]interface{}{
-						"type":        "integer",
-						"minimum":     1,
-						"description": "Branching point thought number",
-					},
-					"branchId": map[string]interface{}{
-						"type":        "string",
-						"description": "Branch identifier",
-					},
-					"needsMoreThoughts": map[string]interface{}{
-						"type":        "boolean",
-						"description": "If more thoughts are needed",
-					},
-				},
-				Required: []string{"thought", "nextThoughtNeeded", "thoughtNumber", "totalThoughts"},
-			},
-		},
-	}, nil
-}
+	var transport = flag.String("transport", "stdio", "Transport type: stdio, sse, http, websocket, or a comma-separated list of network transports such as sse,http")
+	var port = flag.String("port", "8080", "Port for SSE/HTTP servers")
+	var traceExporter = flag.String("trace-exporter", "none", "Trace exporter: none, stdout, or otlp (configured via OTEL_EXPORTER_OTLP_* variables)")
+	var shutdownTimeout = flag.Duration("shutdown-timeout", 30*time.Second, "Time to wait for in-flight requests on shutdown")
+	var authMode = flag.String("auth", "none", "Authentication for SSE/HTTP: none, token, jwt, or mtls")
+	var authTokenFile = flag.String("auth-token-file", "", "File with one \"subject token\" pair per line (for -auth token)")
+	var authJWTKeyFile = flag.String("auth-jwt-key-file", "", "File with the HMAC key used to verify JWTs (for -auth jwt)")
+	var authJWTIssuer = flag.String("auth-jwt-issuer", "", "Required JWT issuer (optional)")
+	var authJWTAudience = flag.String("auth-jwt-audience", "", "Required JWT audience (optional)")
+	var tlsCert = flag.String("tls-cert", "", "TLS certificate file for SSE/HTTP")
+	var tlsKey = flag.String("tls-key", "", "TLS private key file for SSE/HTTP")
+	var tlsClientCA = flag.String("tls-client-ca", "", "CA bundle used to verify client certificates (required for -auth mtls)")
+	var tlsClientAuth = flag.String("tls-client-auth", "", "Client certificate policy: none, request, verify-if-given, or require (default require with -tls-client-ca, none otherwise)")
+	var rateLimit = flag.Float64("rate-limit", 0, "Sustained tool calls per second per client (0 disables)")
+	var rateBurst = flag.Int("rate-burst", 10, "Tool calls a client may burst above -rate-limit")
+	var dailyQuota = flag.Int("daily-quota", 0, "Thoughts per client per UTC day (0 disables)")
+	var addr = flag.String("addr", "", "Host or IP the network transports bind to (default all interfaces)")
+	var basePath = flag.String("base-path", "", "Path prefix of every HTTP endpoint, e.g. /tools/seqthink")
+	var listen = flag.String("listen", "", "Listen address for network transports, unix:///path/to.sock or tcp://host:port (overrides -addr and -port)")
+	var socketMode = flag.String("socket-mode", "0660", "File mode of the unix socket, in octal")
+	var socketGroup = flag.String("socket-group", "", "Group name or ID owning the unix socket")
+	var wsMaxMessage = flag.Int64("ws-max-message", 1<<20, "Largest WebSocket message accepted from a client, in bytes")
+	var wsPingInterval = flag.Duration("ws-ping-interval", 30*time.Second, "Interval between WebSocket keepalive pings (0 disables)")
+	var corsOrigins = flag.String("cors-origins", "", "Comma-separated browser origins allowed to call the MCP endpoints, e.g. https://app.example.com, or * for any")
+	var corsHeaders = flag.String("cors-headers", strings.Join(defaultCORSHeaders, ","), "Comma-separated request headers allowed in CORS requests")
+	var corsCredentials = flag.Bool("cors-credentials", false, "Allow CORS requests with cookies or HTTP authentication")
+	var corsMaxAge = flag.Duration("cors-max-age", 10*time.Minute, "How long browsers may cache CORS preflight results")
+	var enableDashboard = flag.Bool("dashboard", false, "Serve the live web dashboard under /dashboard/ on network transports")
+	var enableRESTAPI = flag.Bool("rest-api", false, "Serve the session REST API under /api/v1/ on network transports")
+	var webhookURLs = flag.String("webhook-urls", "", "Comma-separated URLs that receive session events as signed POST requests")
+	var webhookSecretFile = flag.String("webhook-secret-file", "", "File with the HMAC-SHA256 key used to sign webhook deliveries")
+	var webhookEvents = flag.String("webhook-events", "", "Comma-separated event types to send: "+strings.Join(thinking.WebhookEventTypes, ", ")+" (default all)")
+	var webhookQueue = flag.Int("webhook-queue", 1000, "Undelivered events kept per webhook URL before new ones are dropped")
+	var webhookAttempts = flag.Int("webhook-max-attempts", 5, "Delivery attempts per webhook event before giving up")
+	var callLogPath = flag.String("call-log", "", "Append every tool call to this JSON Lines file and rebuild sessions from it at startup")
+	var tenantHeader = flag.String("tenant-header", "", "Request header naming the tenant when credentials carry none, e.g. X-Tenant-ID; with -auth only -admin-subjects may use it")
+	var maxTenants = flag.Int("max-tenants", 100, "Tenants that may be created on demand besides default and those in -tenant-limits (0 means no limit)")
+	var tenantLimitsFile = flag.String("tenant-limits", "", "JSON file with per-tenant rate limits")
+	var adminSubjects = flag.String("admin-subjects", "", "Comma-separated authenticated subjects allowed to use /admin endpoints")
+	var logLevel = flag.String("log-level", "info", "Log level: debug, info, warn, or error")
+	var logFormat = flag.String("log-format", "text", "Log format: text or json")
+	var redactThoughts = flag.Bool("log-redact-thoughts", false, "Replace thought text in logs with its length")
+	flag.Parse()
 
-// CallTool handles tool execution
-func (s *SequentialThinkingServer) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if request.Params.Name != "sequentialthinking" {
-		return nil, fmt.Errorf("unknown tool: %s", request.Params.Name)
+	// Logs always go to stderr: in stdio mode stdout carries the JSON-RPC stream
+	logger, err := newLogger(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-
-	// Parse arguments from the map format that mcp-go uses
-	var req ThoughtRequest
-
-	// Convert arguments map to JSON and then to our struct
-	if args, ok := request.Params.Arguments.(map[string]interface{}); ok {
-		if thought, exists := args["thought"]; exists {
-			if thoughtStr, ok := thought.(string); ok {
-				req.Thought = thoughtStr
-			}
-		}
-		if nextThoughtNeeded, exists := args["nextThoughtNeeded"]; exists {
-			if nextBool, ok := nextThoughtNeeded.(bool); ok {
-				req.NextThoughtNeeded = nextBool
-			}
-		}
-		if thoughtNumber, exists := args["thoughtNumber"]; exists {
-			if thoughtNum, ok := thoughtNumber.(float64); ok {
-				req.ThoughtNumber = int(thoughtNum)
-			}
+	slog.SetDefault(logger)
+	var tenantLimits map[string]thinking.RateLimitConfig
+	if *tenantLimitsFile != "" {
+		if tenantLimits, err = thinking.LoadTenantLimits(*tenantLimitsFile); err != nil {
+			logger.Error("tenant limits setup failed", "error", err)
+			os.Exit(1)
 		}
-		if totalThoughts, exists := args["totalThoughts"]; exists {
-			if totalNum, ok := totalThoughts.(float64); ok {
-				req.TotalThoughts = int(totalNum)
-			}
-		}
-		if isRevision, exists := args["isRevision"]; exists {
-			if revisionBool, ok := isRevision.(bool); ok {
-				req.IsRevision = revisionBool
-			}
-		}
-		if revisesThought, exists := args["revisesThought"]; exists {
-			if revisesNum, ok := revisesThought.(float64); ok {
-				req.RevisesThought = int(revisesNum)
-			}
-		}
-		if branchFromThought, exists := args["branchFromThought"]; exists {
-			if branchNum, ok := branchFromThought.(float64); ok {
-				req.BranchFromThought = int(branchNum)
-			}
-		}
-		if branchId, exists := args["branchId"]; exists {
-			if branchStr, ok := branchId.(string); ok {
-				req.BranchID = branchStr
-			}
-		}
-		if needsMoreThoughts, exists := args["needsMoreThoughts"]; exists {
-			if needsBool, ok := needsMoreThoughts.(bool); ok {
-				req.NeedsMoreThoughts = needsBool
-			}
+	}
+	webhooks := thinking.WebhookConfig{
+		URLs:        splitList(*webhookURLs),
+		Events:      splitList(*webhookEvents),
+		QueueSize:   *webhookQueue,
+		MaxAttempts: *webhookAttempts,
+		UserAgent:   "sequentialthinking-webhook/" + version,
+	}
+	if *webhookSecretFile != "" {
+		if webhooks.Secret, err = thinking.LoadWebhookSecret(*webhookSecretFile); err != nil {
+			logger.Error("webhook setup failed", "error", err)
+			os.Exit(1)
 		}
-	} else {
-		// Fallback: try to unmarshal as JSON (for testing)
-		argsBytes, err := json.Marshal(request.Params.Arguments)
+	}
+	if err := webhooks.Validate(); err != nil {
+		logger.Error("webhook setup failed", "error", err)
+		os.Exit(1)
+	}
+	opts := []thinking.Option{
+		thinking.WithLogger(logger),
+		thinking.WithThoughtRedaction(*redactThoughts),
+		thinking.WithRateLimit(thinking.RateLimitConfig{Rate: *rateLimit, Burst: *rateBurst, DailyQuota: *dailyQuota}),
+		thinking.WithTenantLimits(tenantLimits),
+		thinking.WithMaxTenants(*maxTenants),
+		thinking.WithWebhooks(webhooks),
+	}
+	var callRecords []thinking.CallRecord
+	if *callLogPath != "" {
+		callLog, records, err := thinking.OpenCallLog(*callLogPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal arguments: %w", err)
-		}
-		if err := json.Unmarshal(argsBytes, &req); err != nil {
-			return nil, fmt.Errorf("invalid arguments: %w", err)
+			logger.Error("call log setup failed", "error", err)
+			os.Exit(1)
 		}
+		opts = append(opts, thinking.WithCallLog(callLog))
+		callRecords = records
 	}
-
-	// Validate input
-	if err := s.validateThoughtRequest(&req); err != nil {
-		return nil, fmt.Errorf("validation error: %w", err)
+	mcpServer := newMCPServer()
+	thinker := thinking.Register(mcpServer, opts...)
+	if err := thinker.RestoreSessions(callRecords); err != nil {
+		logger.Error("call log setup failed", "error", err)
+		os.Exit(1)
 	}
-
-	// Process the thought
-	sessionID := fmt.Sprintf("session_%d", time.Now().Unix())
-	if s.history[sessionID] == nil {
-		s.history[sessionID] = &ThoughtHistory{
-			Thoughts:  []ThoughtRequest{},
-			Branches:  make(map[string][]int),
-			CreatedAt: time.Now(),
-		}
+	if len(callRecords) > 0 {
+		logger.Info("rebuilt sessions from call log", "path", *callLogPath, "records", len(callRecords))
 	}
 
-	s.history[sessionID].Thoughts = append(s.history[sessionID].Thoughts, req)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Handle branching
-	if req.BranchID != "" {
-		if s.history[sessionID].Branches[req.BranchID] == nil {
-			s.history[sessionID].Branches[req.BranchID] = []int{}
-		}
-		s.history[sessionID].Branches[req.BranchID] = append(
-			s.history[sessionID].Branches[req.BranchID],
-			req.ThoughtNumber,
-		)
+	shutdownTracing, err := setupTracing(ctx, *traceExporter)
+	if err != nil {
+		logger.Error("tracing setup failed", "error", err)
+		os.Exit(1)
 	}
 
-	// Format response
-	response := s.formatThoughtResponse(&req, sessionID)
+	auth, err := newAuthenticator(authConfig{
+		mode:        *authMode,
+		tokenFile:   *authTokenFile,
+		jwtKeyFile:  *authJWTKeyFile,
+		jwtIssuer:   *authJWTIssuer,
+		jwtAudience: *authJWTAudience,
+	})
+	if err != nil {
+		logger.Error("authentication setup failed", "error", err)
+		os.Exit(1)
+	}
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: response,
-			},
+	// Shutting down the listener and draining tool calls share one deadline
+	deadline := newShutdownDeadline(*shutdownTimeout)
+	defer deadline.stop()
+
+	httpCfg := httpConfig{
+		listen:       listenConfig{network: "tcp", address: net.JoinHostPort(*addr, *port), socketGroup: *socketGroup},
+		deadline:     deadline,
+		auth:         auth,
+		tenantHeader: *tenantHeader,
+		dashboard:    *enableDashboard,
+		restAPI:      *enableRESTAPI,
+		admins:       make(map[string]bool),
+		websocket: websocketOptions{
+			maxMessageSize: *wsMaxMessage,
+			pingInterval:   *wsPingInterval,
 		},
-	}, nil
-}
-
-// validateThoughtRequest validates the thought request parameters
-func (s *SequentialThinkingServer) validateThoughtRequest(req *ThoughtRequest) error {
-	if req.Thought == "" {
-		return fmt.Errorf("thought cannot be empty")
 	}
-	if req.ThoughtNumber < 1 {
-		return fmt.Errorf("thought number must be positive")
+	if *listen != "" {
+		if httpCfg.listen.network, httpCfg.listen.address, err = parseListenAddress(*listen); err != nil {
+			logger.Error("listener setup failed", "error", err)
+			os.Exit(1)
+		}
 	}
-	if req.TotalThoughts < 1 {
-		return fmt.Errorf("total thoughts must be positive")
+	if httpCfg.basePath, err = normalizeBasePath(*basePath); err != nil {
+		logger.Error("listener setup failed", "error", err)
+		os.Exit(1)
 	}
-	if req.ThoughtNumber > req.TotalThoughts && !req.NeedsMoreThoughts {
-		return fmt.Errorf("thought number cannot exceed total thoughts unless more thoughts are needed")
+	mode, err := strconv.ParseUint(*socketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		logger.Error("listener setup failed", "error", fmt.Errorf("invalid -socket-mode %q", *socketMode))
+		os.Exit(1)
 	}
-	if req.IsRevision && req.RevisesThought < 1 {
-		return fmt.Errorf("revises thought must be specified for revisions")
+	httpCfg.listen.socketMode = os.FileMode(mode)
+	for _, subject := range splitList(*adminSubjects) {
+		httpCfg.admins[subject] = true
 	}
-	return nil
-}
-
-// formatThoughtResponse formats the response for a thought
-func (s *SequentialThinkingServer) formatThoughtResponse(req *ThoughtRequest, sessionID string) string {
-	response := fmt.Sprintf("🤔 **Thought %d/%d**", req.ThoughtNumber, req.TotalThoughts)
-
-	if req.IsRevision {
-		response += fmt.Sprintf(" (Revision of Thought %d)", req.RevisesThought)
+	if httpCfg.cors, err = newCORSConfig(*corsOrigins, *corsHeaders, *corsCredentials, *corsMaxAge); err != nil {
+		logger.Error("CORS setup failed", "error", err)
+		os.Exit(1)
 	}
-
-	if req.BranchID != "" {
-		response += fmt.Sprintf(" [Branch: %s]", req.BranchID)
+	if len(httpCfg.admins) > 0 && auth == nil {
+		logger.Error("-admin-subjects requires -auth")
+		os.Exit(1)
 	}
-
-	response += fmt.Sprintf("\n\n%s", req.Thought)
-
-	if req.NextThoughtNeeded {
-		response += "\n\n*Continuing to next thought...*"
-	} else {
-		response += "\n\n✅ **Thinking process completed**"
-
-		// Add summary of the thinking process
-		if history := s.history[sessionID]; history != nil && len(history.Thoughts) > 1 {
-			response += fmt.Sprintf("\n\n📊 **Summary**: Completed %d thoughts", len(history.Thoughts))
-
-			if len(history.Branches) > 0 {
-				response += fmt.Sprintf(" across %d branches", len(history.Branches))
-			}
+	if *tlsCert != "" || *tlsKey != "" {
+		httpCfg.tls, err = newTLSConfig(tlsOptions{
+			certFile:     *tlsCert,
+			keyFile:      *tlsKey,
+			clientCAFile: *tlsClientCA,
+			clientAuth:   *tlsClientAuth,
+		})
+		if err != nil {
+			logger.Error("TLS setup failed", "error", err)
+			os.Exit(1)
 		}
 	}
-
-	if req.NeedsMoreThoughts {
-		response += "\n\n🔄 **Note**: Additional thoughts may be needed to fully explore this problem."
+	if *authMode == "mtls" && (httpCfg.tls == nil || httpCfg.tls.ClientCAs == nil ||
+		(httpCfg.tls.ClientAuth != tls.VerifyClientCertIfGiven && httpCfg.tls.ClientAuth != tls.RequireAndVerifyClientCert)) {
+		logger.Error("-auth mtls requires -tls-cert, -tls-key, -tls-client-ca and a verifying -tls-client-auth mode")
+		os.Exit(1)
 	}
 
-	return response
-}
-
-// ListResources returns the available resources (none for this server)
-func (s *SequentialThinkingServer) ListResources(ctx context.Context) ([]mcp.Resource, error) {
-	return []mcp.Resource{}, nil
-}
-
-// ReadResource reads a resource (not implemented for this server)
-func (s *SequentialThinkingServer) ReadResource(ctx context.Context, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
-	return nil, fmt.Errorf("no resources available")
-}
-
-// ListPrompts returns the available prompts (none for this server)
-func (s *SequentialThinkingServer) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
-	return []mcp.Prompt{}, nil
-}
-
-// GetPrompt gets a prompt (not implemented for this server)
-func (s *SequentialThinkingServer) GetPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
-	return nil, fmt.Errorf("no prompts available")
-}
-
-func main() {
-	var transport = flag.String("transport", "stdio", "Transport type: stdio, sse, or http")
-	var port = flag.String("port", "8080", "Port for SSE/HTTP servers")
-	flag.Parse()
-
-	// Create server with proper configuration
-	mcpServer := server.NewMCPServer(
-		"sequentialthinking",
-		"1.0.0",
-		server.WithToolCapabilities(true),
-		server.WithLogging(),
-	)
-
-	// Add the sequential thinking tool
-	mcpServer.AddTool(
-		mcp.NewTool("sequentialthinking",
-			mcp.WithDescription("A detailed tool for dynamic and reflective problem-solving through thoughts.\nThis tool helps analyze problems through a flexible thinking process that can adapt and evolve.\nEach thought can build on, question, or revise previous insights as understanding deepens."),
-			mcp.WithString("thought",
-				mcp.Description("Your current thinking step"),
-				mcp.Required(),
-			),
-			mcp.WithBoolean("nextThoughtNeeded",
-				mcp.Description("Whether another thought step is needed"),
-				mcp.Required(),
-			),
-			mcp.WithNumber("thoughtNumber",
-				mcp.Description("Current thought number"),
-				mcp.Required(),
-			),
-			mcp.WithNumber("totalThoughts",
-				mcp.Description("Estimated total thoughts needed"),
-				mcp.Required(),
-			),
-			mcp.WithBoolean("isRevision",
-				mcp.Description("Whether this revises previous thinking"),
-			),
-			mcp.WithNumber("revisesThought",
-				mcp.Description("Which thought is being reconsidered"),
-			),
-			mcp.WithNumber("branchFromThought",
-				mcp.Description("Branching point thought number"),
-			),
-			mcp.WithString("branchId",
-				mcp.Description("Branch identifier"),
-			),
-			mcp.WithBoolean("needsMoreThoughts",
-				mcp.Description("If more thoughts are needed"),
-			),
-		),
-		handleSequentialThinking,
-	)
-
-	switch *transport {
-	case "stdio":
-		log.Println("Starting MCP server with STDIO transport...")
-		if err := server.ServeStdio(mcpServer); err != nil {
-			log.Fatal("STDIO server error:", err)
-		}
-
-	case "sse":
-		log.Printf("Starting MCP server with SSE transport on port %s...", *port)
-		sseServer := server.NewSSEServer(mcpServer)
-
-		http.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
-			sseServer.ServeHTTP(w, r)
-		})
-
-		if err := http.ListenAndServe(":"+*port, nil); err != nil {
-			log.Fatal("SSE server error:", err)
-		}
-
-	case "http":
-		log.Printf("Starting MCP server with streamable HTTP transport on port %s...", *port)
-		httpServer := server.NewStreamableHTTPServer(mcpServer)
-
-		log.Printf("HTTP server listening on :%s/mcp", *port)
-		if err := httpServer.Start(":" + *port); err != nil {
-			log.Fatal("HTTP server error:", err)
-		}
-
-	default:
-		fmt.Fprintf(os.Stderr, "Unknown transport: %s\n", *transport)
-		fmt.Fprintf(os.Stderr, "Usage: %s [-transport stdio|sse|http] [-port PORT]\n", os.Args[0])
+	transports, err := parseTransports(*transport)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintf(os.Stderr, "Usage: %s [-transport stdio|sse|http|websocket|sse,http] [-port PORT] [-shutdown-timeout DURATION]\n", os.Args[0])
 		os.Exit(1)
 	}
-}
 
-// Global server instance for tool handling
-var globalServer = NewSequentialThinkingServer()
+	if transports[0] == "stdio" {
+		logger.Info("starting MCP server", "transport", "stdio")
+		err = runStdio(ctx, mcpServer, os.Stdin, os.Stdout)
+	} else {
+		logger.Info("starting MCP server", "transport", *transport, "listen", httpCfg.listen.String())
+		err = runNetwork(ctx, mcpServer, thinker, httpCfg, transports)
+	}
+	// The session store is flushed even when serving failed or the
+	// listener did not shut down in time; the error decides the exit code
+	failed := false
+	if err != nil {
+		logger.Error("server error", "transport", *transport, "error", err)
+		failed = true
+	}
 
-// handleSequentialThinking handles the sequential thinking tool calls
-func handleSequentialThinking(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return globalServer.CallTool(ctx, request)
+	logger.Info("draining in-flight tool calls", "timeout", *shutdownTimeout)
+	if err := thinker.Shutdown(deadline.context()); err != nil {
+		logger.Error("shutdown failed", "error", err)
+		failed = true
+	}
+	if err := shutdownTracing(deadline.context()); err != nil {
+		logger.Warn("tracing shutdown failed", "error", err)
+	}
+	if failed {
+		deadline.stop()
+		os.Exit(1)
+	}
+	logger.Info("server stopped")
 }
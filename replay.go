@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// replayEpoch dates transcript calls that carry no time of their own, so
+// they all land in one session
+var replayEpoch = time.Unix(1700000000, 0).UTC()
+
+// readTranscript parses a JSON Lines transcript of tool calls. Each line is
+// either a call log record or a JSON-RPC message as sent over stdio; JSON-RPC
+// messages other than tools/call are skipped.
+func readTranscript(r io.Reader) ([]thinking.CallRecord, error) {
+	var calls []thinking.CallRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg struct {
+			Method string `json:"method"`
+			Params struct {
+				Name      string          `json:"name"`
+				Arguments json.RawMessage `json:"arguments"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if msg.Method != "" {
+			if msg.Method == string(mcp.MethodToolsCall) {
+				calls = append(calls, thinking.CallRecord{Tool: msg.Params.Name, Arguments: msg.Params.Arguments})
+			}
+			continue
+		}
+
+		var rec thinking.CallRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if rec.Tool == "" {
+			return nil, fmt.Errorf("line %d: neither a call record nor a JSON-RPC message", lineNo)
+		}
+		calls = append(calls, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return calls, nil
+}
+
+// replayCalls feeds calls through a fresh thinking.SequentialThinkingServer and
+// renders its responses as text. Each call runs at its recorded time, as
+// the recorded tenant and principal, so it reaches the same session it
+// originally did. Calls the original server refused before looking at them
+// (rate limited, rejected by middleware or shutting down) are skipped, and
+// recorded session deletions are applied in turn. Replay skips middleware:
+// calls replay with the arguments the call log holds, which are the thought
+// as middleware rewrote it, but a call middleware moved to another session
+// replays in its original one.
+func replayCalls(calls []thinking.CallRecord) string {
+	var clock time.Time
+	thinker := thinking.NewSequentialThinkingServer(
+		thinking.WithLogger(slog.New(slog.DiscardHandler)),
+		thinking.WithClock(func() time.Time { return clock }),
+	)
+
+	var b strings.Builder
+	for i, call := range calls {
+		switch call.Outcome {
+		case thinking.OutcomeRateLimited, thinking.OutcomeRejected, thinking.OutcomeUnavailable:
+			continue
+		}
+		clock = call.Time
+		if clock.IsZero() {
+			clock = replayEpoch
+		}
+
+		if call.Tool == thinking.DeleteSessionRecord {
+			if err := thinker.DeleteSession(replayContext(call), call.SessionID); err != nil {
+				fmt.Fprintf(&b, "=== %d %s (error)\n%s\n", i+1, call.Tool, err)
+			} else {
+				fmt.Fprintf(&b, "=== %d %s\n%s\n", i+1, call.Tool, call.SessionID)
+			}
+			continue
+		}
+
+		var args any
+		if len(call.Arguments) > 0 {
+			if err := json.Unmarshal(call.Arguments, &args); err != nil {
+				args = string(call.Arguments)
+			}
+		}
+		result, err := thinker.CallTool(replayContext(call), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: call.Tool, Arguments: args},
+		})
+
+		switch {
+		case err != nil:
+			fmt.Fprintf(&b, "=== %d %s (error)\n%s\n", i+1, call.Tool, err)
+		case result.IsError:
+			fmt.Fprintf(&b, "=== %d %s (tool error)\n%s\n", i+1, call.Tool, resultText(result))
+		default:
+			fmt.Fprintf(&b, "=== %d %s\n%s\n", i+1, call.Tool, resultText(result))
+		}
+	}
+
+	return b.String()
+}
+
+// replayContext recreates the caller of a recorded call
+func replayContext(call thinking.CallRecord) context.Context {
+	ctx := context.Background()
+	if subject, ok := strings.CutPrefix(call.Client, "principal:"); ok {
+		return thinking.ContextWithPrincipal(ctx, &thinking.Principal{Subject: subject, Method: "replay", Tenant: call.Tenant})
+	}
+	if call.Tenant != "" {
+		return thinking.ContextWithTenant(ctx, call.Tenant)
+	}
+
+	return ctx
+}
+
+// resultText joins the text content of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// diffLines returns a line diff turning want into got, with "-" marking
+// lines only in want and "+" lines only in got; it is empty when they match
+func diffLines(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var d strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&d, "  %s\n", a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			fmt.Fprintf(&d, "+ %s\n", b[j])
+			j++
+		default:
+			fmt.Fprintf(&d, "- %s\n", a[i])
+			i++
+		}
+	}
+
+	return d.String()
+}
+
+// errGoldenMismatch is returned when replayed responses differ from the golden file
+var errGoldenMismatch = errors.New("responses differ from golden file")
+
+// replayTranscript replays the transcript at path and compares the
+// responses with the golden file, rewriting it instead when update is set.
+// Without a golden file the responses are written to out.
+func replayTranscript(path, golden string, update bool, out io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	calls, err := readTranscript(file)
+	if err != nil {
+		return fmt.Errorf("reading transcript %s: %w", path, err)
+	}
+	got := replayCalls(calls)
+
+	switch {
+	case golden == "":
+		_, err := io.WriteString(out, got)
+		return err
+	case update:
+		return os.WriteFile(golden, []byte(got), 0o644)
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		return err
+	}
+	if diff := diffLines(string(want), got); diff != "" {
+		fmt.Fprintf(out, "--- %s\n+++ replay of %s\n%s", golden, path, diff)
+		return errGoldenMismatch
+	}
+
+	return nil
+}
+
+// runReplay implements the replay subcommand and returns the exit code:
+//
+//	sequentialthinking-server replay [-golden FILE [-update]] TRANSCRIPT
+func runReplay(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	golden := fs.String("golden", "", "Golden file to compare the responses with (default print them)")
+	update := fs.Bool("update", false, "Rewrite the golden file with the replayed responses")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s replay [-golden FILE [-update]] TRANSCRIPT.jsonl\n\nTRANSCRIPT holds one call log record or JSON-RPC message per line.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || (*update && *golden == "") {
+		fs.Usage()
+		return 2
+	}
+
+	if err := replayTranscript(fs.Arg(0), *golden, *update, stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files with the current responses")
+
+// TestReplayGolden replays every transcript in testdata/replay and compares
+// the responses with the .golden file next to it. Run with -update after an
+// intended change to the responses.
+func TestReplayGolden(t *testing.T) {
+	transcripts, err := filepath.Glob(filepath.Join("testdata", "replay", "*.jsonl"))
+	if err != nil || len(transcripts) == 0 {
+		t.Fatalf("No transcripts found: %v", err)
+	}
+
+	for _, transcript := range transcripts {
+		name := strings.TrimSuffix(filepath.Base(transcript), ".jsonl")
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			golden := strings.TrimSuffix(transcript, ".jsonl") + ".golden"
+			if err := replayTranscript(transcript, golden, *updateGolden, &out); err != nil {
+				t.Fatalf("%v\n%s", err, out.String())
+			}
+		})
+	}
+}
+
+func TestReplayIsDeterministic(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "replay", "calllog.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	calls, err := readTranscript(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first, second := replayCalls(calls), replayCalls(calls); first != second {
+		t.Errorf("Replays differ:\n%s", diffLines(first, second))
+	}
+}
+
+func TestReplayReportsMismatch(t *testing.T) {
+	dir := t.TempDir()
+	transcript := filepath.Join(dir, "t.jsonl")
+	golden := filepath.Join(dir, "t.golden")
+	call := `{"tool":"sequentialthinking","arguments":{"thought":"Only","nextThoughtNeeded":false,"thoughtNumber":1,"totalThoughts":1}}`
+	if err := os.WriteFile(transcript, []byte(call+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := runReplay([]string{"-golden", golden, "-update", transcript}, &bytes.Buffer{}, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("Updating the golden file exited with %d", code)
+	}
+
+	data, _ := os.ReadFile(golden)
+	if err := os.WriteFile(golden, bytes.Replace(data, []byte("Only"), []byte("Changed"), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err := replayTranscript(transcript, golden, false, &out)
+	if !errors.Is(err, errGoldenMismatch) {
+		t.Fatalf("Expected a mismatch, got %v", err)
+	}
+	if !strings.Contains(out.String(), "- Changed") || !strings.Contains(out.String(), "+ Only") {
+		t.Errorf("Diff does not show the change:\n%s", out.String())
+	}
+}
+
+func TestReadTranscriptRejectsUnknownLines(t *testing.T) {
+	if _, err := readTranscript(strings.NewReader(`{"foo":1}` + "\n")); err == nil {
+		t.Error("Expected an error for a line that is neither a record nor JSON-RPC")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// unsafeFilenameChars are replaced when a session ID becomes a file name
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// restAPI serves session data over plain HTTP for clients that do not speak
+// MCP. It reads the same per-tenant stores the sequentialthinking tool
+// writes to, and every request only sees the caller's tenant.
+type restAPI struct {
+	thinker *thinking.SequentialThinkingServer
+	cfg     httpConfig
+}
+
+// register mounts the API under /api/v1/. Session IDs containing "/" must be
+// sent escaped as %2F.
+func (a *restAPI) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPIDocument)
+	})
+	mux.Handle("/api/v1/sessions", a.guard(map[string]http.HandlerFunc{
+		http.MethodGet: a.handleList,
+	}))
+	mux.Handle("/api/v1/sessions/{id}", a.guard(map[string]http.HandlerFunc{
+		http.MethodGet:    a.handleGet,
+		http.MethodDelete: a.handleDelete,
+	}))
+	mux.Handle("/api/v1/sessions/{id}/export", a.guard(map[string]http.HandlerFunc{
+		http.MethodGet: a.handleExport,
+	}))
+}
+
+// guard dispatches by method behind CORS and authentication. Methods are
+// matched here rather than in the mux patterns so CORS preflights reach the
+// CORS handler instead of being refused by the mux.
+func (a *restAPI) guard(handlers map[string]http.HandlerFunc) http.Handler {
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	dispatch := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handler(w, r)
+	})
+
+	return a.cfg.cors.handler(requireAuth(a.cfg.auth, dispatch))
+}
+
+// writeAPIError writes a JSON error body
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// handleList lists the caller's sessions, newest first
+func (a *restAPI) handleList(w http.ResponseWriter, r *http.Request) {
+	store, err := a.cfg.storeOf(a.thinker, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listSessions(r.Context(), store))
+}
+
+// handleGet returns one session with all its thoughts
+func (a *restAPI) handleGet(w http.ResponseWriter, r *http.Request) {
+	id, history, ok := a.session(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, thinking.Session{ID: id, ThoughtHistory: history})
+}
+
+// handleDelete removes one of the caller's sessions
+func (a *restAPI) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if _, err := a.cfg.storeOf(a.thinker, r); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// DeleteSession also records the deletion in the call log
+	ctx := a.cfg.requestContext(r.Context(), r)
+	switch err := a.thinker.DeleteSession(ctx, r.PathValue("id")); {
+	case errors.Is(err, thinking.ErrSessionNotFound):
+		writeAPIError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeAPIError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleExport returns a session as a downloadable document
+func (a *restAPI) handleExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	exported, ok := thinking.ExportFormats[format]
+	if !ok {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("unsupported format %q, use json or markdown", format))
+		return
+	}
+
+	id, history, ok := a.session(w, r)
+	if !ok {
+		return
+	}
+
+	filename := strings.Trim(unsafeFilenameChars.ReplaceAllString(id, "_"), "_") + "." + exported.Extension
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		writeJSON(w, http.StatusOK, thinking.Session{ID: id, ThoughtHistory: history})
+		return
+	}
+	w.Header().Set("Content-Type", exported.ContentType)
+	_, _ = w.Write([]byte(thinking.SessionMarkdown(id, history)))
+}
+
+// session loads the session named in the path, writing the error response
+// itself when that fails. Sessions of other subjects are reported missing.
+func (a *restAPI) session(w http.ResponseWriter, r *http.Request) (string, *thinking.ThoughtHistory, bool) {
+	store, err := a.cfg.storeOf(a.thinker, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return "", nil, false
+	}
+
+	id := r.PathValue("id")
+	if !thinking.OwnsSession(r.Context(), id) {
+		writeAPIError(w, http.StatusNotFound, thinking.ErrSessionNotFound.Error())
+		return "", nil, false
+	}
+	history, ok := store.Get(id)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, thinking.ErrSessionNotFound.Error())
+		return "", nil, false
+	}
+
+	return id, history, true
+}
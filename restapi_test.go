@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// startRESTAPI serves the REST API of thinker, with tenants taken from X-Tenant-ID
+func startRESTAPI(t *testing.T, thinker *thinking.SequentialThinkingServer) *httptest.Server {
+	t.Helper()
+
+	cfg := httpConfig{restAPI: true, tenantHeader: "X-Tenant-ID"}
+	mux := http.NewServeMux()
+	cfg.registerCommonHandlers(mux, &http.Server{}, thinker)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// apiRequest calls the API as tenant and returns the status and body
+func apiRequest(t *testing.T, method, url, tenant string) (int, http.Header, string) {
+	t.Helper()
+
+	r, _ := http.NewRequest(method, url, nil)
+	r.Header.Set("X-Tenant-ID", tenant)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	return resp.StatusCode, resp.Header, string(body)
+}
+
+func TestRESTAPISessions(t *testing.T) {
+	thinker := thinking.NewSequentialThinkingServer()
+	alice := thinking.ContextWithPrincipal(context.Background(), &thinking.Principal{Subject: "alice", Tenant: "acme"})
+	calls := []map[string]interface{}{
+		{"thought": "Frame the problem", "nextThoughtNeeded": true, "thoughtNumber": float64(1), "totalThoughts": float64(2)},
+		{"thought": "Reframe it", "nextThoughtNeeded": true, "thoughtNumber": float64(2), "totalThoughts": float64(2), "isRevision": true, "revisesThought": float64(1)},
+		{"thought": "Conclude", "nextThoughtNeeded": false, "thoughtNumber": float64(2), "totalThoughts": float64(2)},
+	}
+	for _, args := range calls {
+		request := thoughtCall(1, true)
+		request.Params.Arguments = args
+		if _, err := thinker.CallTool(alice, request); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+	}
+	ts := startRESTAPI(t, thinker)
+	base := ts.URL + "/api/v1/sessions"
+
+	status, _, body := apiRequest(t, "GET", base, "acme")
+	var sessions []SessionSummary
+	if err := json.Unmarshal([]byte(body), &sessions); err != nil || status != http.StatusOK {
+		t.Fatalf("Listing sessions: status %d, body %s, err %v", status, body, err)
+	}
+	if len(sessions) != 1 || sessions[0].Thoughts != 3 || sessions[0].Revisions != 1 || !sessions[0].Complete {
+		t.Fatalf("Unexpected sessions: %+v", sessions)
+	}
+
+	// The session ID contains "/", which must travel escaped
+	id := sessions[0].ID
+	sessionURL := base + "/" + url.PathEscape(id)
+
+	status, _, body = apiRequest(t, "GET", sessionURL, "acme")
+	var session thinking.Session
+	if err := json.Unmarshal([]byte(body), &session); err != nil || status != http.StatusOK {
+		t.Fatalf("Getting session: status %d, body %s, err %v", status, body, err)
+	}
+	if session.ID != id || len(session.Thoughts) != 3 {
+		t.Errorf("Unexpected session: %+v", session)
+	}
+
+	status, header, body := apiRequest(t, "GET", sessionURL+"/export?format=markdown", "acme")
+	if status != http.StatusOK || !strings.HasPrefix(header.Get("Content-Type"), "text/markdown") {
+		t.Fatalf("Markdown export: status %d, content type %s", status, header.Get("Content-Type"))
+	}
+	for _, want := range []string{"# Session " + id, "## Thought 2/2 (revises thought 1)", "Conclude"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Markdown export lacks '%s':\n%s", want, body)
+		}
+	}
+	if cd := header.Get("Content-Disposition"); !strings.Contains(cd, `filename="alice_session_`) {
+		t.Errorf("Unexpected Content-Disposition '%s'", cd)
+	}
+
+	if status, _, _ := apiRequest(t, "GET", sessionURL+"/export", "acme"); status != http.StatusOK {
+		t.Errorf("Expected JSON export by default, got %d", status)
+	}
+	if status, _, _ := apiRequest(t, "GET", sessionURL+"/export?format=pdf", "acme"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown format, got %d", status)
+	}
+
+	// Other tenants can neither read nor delete the session
+	if status, _, _ := apiRequest(t, "GET", sessionURL, "globex"); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for another tenant, got %d", status)
+	}
+	if status, _, _ := apiRequest(t, "DELETE", sessionURL, "globex"); status != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting from another tenant, got %d", status)
+	}
+
+	if status, _, _ := apiRequest(t, "DELETE", sessionURL, "acme"); status != http.StatusNoContent {
+		t.Errorf("Expected 204 on delete, got %d", status)
+	}
+	if status, _, _ := apiRequest(t, "GET", sessionURL, "acme"); status != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", status)
+	}
+
+	status, header, _ = apiRequest(t, "PUT", sessionURL, "acme")
+	if status != http.StatusMethodNotAllowed || header.Get("Allow") != "DELETE, GET" {
+		t.Errorf("Expected 405 with Allow header, got %d '%s'", status, header.Get("Allow"))
+	}
+}
+
+func TestRESTAPIHidesOtherSubjectsSessions(t *testing.T) {
+	thinker := thinking.NewSequentialThinkingServer()
+	alice := thinking.ContextWithPrincipal(context.Background(), &thinking.Principal{Subject: "alice", Tenant: "acme"})
+	if _, err := thinker.CallTool(alice, thoughtCall(1, true)); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	auth, err := newTokenAuthenticator(writeTempFile(t, "tokens", "alice alice-token acme\nbob bob-token acme\n"))
+	if err != nil {
+		t.Fatalf("newTokenAuthenticator failed: %v", err)
+	}
+	cfg := httpConfig{restAPI: true, auth: auth}
+	mux := http.NewServeMux()
+	cfg.registerCommonHandlers(mux, &http.Server{}, thinker)
+
+	call := func(method, path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		return rec
+	}
+
+	store, err := thinker.Store("acme")
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	ids := store.List()
+	if len(ids) != 1 {
+		t.Fatalf("Expected one session, got %v", ids)
+	}
+	sessionURL := "/api/v1/sessions/" + url.PathEscape(ids[0])
+
+	// bob shares the tenant but can neither list, read, export nor delete
+	// alice's session
+	if body := strings.TrimSpace(call("GET", "/api/v1/sessions", "bob-token").Body.String()); body != "[]" {
+		t.Errorf("Expected bob to see no sessions, got %s", body)
+	}
+	for _, req := range []struct{ method, path string }{
+		{"GET", sessionURL},
+		{"GET", sessionURL + "/export"},
+		{"DELETE", sessionURL},
+	} {
+		if rec := call(req.method, req.path, "bob-token"); rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s as bob: expected 404, got %d", req.method, req.path, rec.Code)
+		}
+	}
+
+	if rec := call("GET", sessionURL, "alice-token"); rec.Code != http.StatusOK {
+		t.Errorf("Expected alice to read the session, got %d", rec.Code)
+	}
+	if rec := call("DELETE", sessionURL, "alice-token"); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected alice to delete the session, got %d", rec.Code)
+	}
+}
+
+func TestRESTAPIOpenAPIDocument(t *testing.T) {
+	ts := startRESTAPI(t, thinking.NewSequentialThinkingServer())
+
+	status, _, body := apiRequest(t, "GET", ts.URL+"/api/v1/openapi.json", "")
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatalf("OpenAPI document is not valid JSON: %v", err)
+	}
+	for _, path := range []string{"/sessions", "/sessions/{id}", "/sessions/{id}/export"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("OpenAPI document does not describe %s", path)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// runStdio serves MCP over in and out, normally stdin and stdout, until ctx
+// is cancelled or in is closed
+func runStdio(ctx context.Context, mcpServer *server.MCPServer, in io.Reader, out io.Writer) error {
+	stdioServer := server.NewStdioServer(mcpServer)
+
+	err := stdioServer.Listen(ctx, in, out)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	return err
+}
+
+// httpConfig holds the listener settings shared by the network transports
+type httpConfig struct {
+	listen listenConfig
+	// deadline bounds the HTTP shutdown together with the drain after it
+	deadline *shutdownDeadline
+	// basePath prefixes every endpoint, e.g. "/tools/seqthink"; empty
+	// serves from the root
+	basePath string
+	// auth guards the MCP endpoints; nil disables authentication
+	auth Authenticator
+	// tls enables HTTPS when set
+	tls *tls.Config
+	// tenantHeader names the request header carrying the tenant, if any
+	tenantHeader string
+	// admins are the subjects allowed to use the /admin endpoints; the
+	// endpoints are not mounted when empty
+	admins map[string]bool
+	// websocket tunes the WebSocket transport
+	websocket websocketOptions
+	// cors lets browser pages on other origins call the MCP endpoints
+	cors corsConfig
+	// dashboard serves the web UI under /dashboard/
+	dashboard bool
+	// restAPI serves the session REST API under /api/v1/
+	restAPI bool
+}
+
+// withRemoteAddr records the caller's network address in ctx. Peers on a
+// Unix socket all share one address, so they are left to be told apart by
+// their MCP session.
+func withRemoteAddr(ctx context.Context, r *http.Request) context.Context {
+	if local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && local.Network() == "unix" {
+		return ctx
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return thinking.ContextWithRemoteAddr(ctx, host)
+}
+
+// requestContext carries per-request data from the HTTP request into tool calls
+func (c httpConfig) requestContext(ctx context.Context, r *http.Request) context.Context {
+	ctx = extractTraceContext(ctx, r)
+	ctx = withRemoteAddr(ctx, r)
+
+	return withTenantHeader(ctx, r, c.tenantHeader, c.admins)
+}
+
+// tenantOf resolves the tenant whose sessions the request may access
+func (c httpConfig) tenantOf(r *http.Request) (string, error) {
+	return thinking.TenantFromContext(c.requestContext(r.Context(), r))
+}
+
+// storeOf returns the session store of the request's tenant
+func (c httpConfig) storeOf(thinker *thinking.SequentialThinkingServer, r *http.Request) (thinking.SessionStore, error) {
+	id, err := c.tenantOf(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return thinker.Store(id)
+}
+
+// path returns the externally visible path of an endpoint
+func (c httpConfig) path(endpoint string) string {
+	return c.basePath + endpoint
+}
+
+// registerCommonHandlers mounts the endpoints served next to the MCP endpoints
+func (c httpConfig) registerCommonHandlers(mux *http.ServeMux, httpServer *http.Server, thinker *thinking.SequentialThinkingServer) {
+	common := http.NewServeMux()
+	registerHealthHandlers(common, thinker)
+	common.Handle("/metrics", thinker.MetricsHandler())
+	if len(c.admins) > 0 {
+		common.Handle("/admin/tenants", requireAuth(c.auth, handleAdminTenants(thinker, c.admins)))
+	}
+	if c.restAPI {
+		(&restAPI{thinker: thinker, cfg: c}).register(common)
+	}
+	if c.dashboard {
+		d := newDashboard(thinker, c)
+		d.register(common)
+		httpServer.RegisterOnShutdown(d.close)
+	}
+
+	mux.Handle(c.path("/"), http.StripPrefix(c.basePath, common))
+}
+
+// normalizeBasePath turns a -base-path value into "" or "/segment[/...]"
+// without a trailing slash
+func normalizeBasePath(value string) (string, error) {
+	if strings.ContainsAny(value, "?#{} ") {
+		return "", fmt.Errorf("invalid base path %q", value)
+	}
+
+	value = strings.Trim(value, "/")
+	if value == "" {
+		return "", nil
+	}
+
+	return "/" + value, nil
+}
+
+// parseTransports splits a comma-separated -transport value such as
+// "sse,http". stdio owns the process's stdin and stdout, so it cannot be
+// combined with the network transports.
+func parseTransports(value string) ([]string, error) {
+	var transports []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "stdio", "sse", "http", "websocket":
+		default:
+			return nil, fmt.Errorf("unknown transport: %q", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			transports = append(transports, name)
+		}
+	}
+	if seen["stdio"] && len(transports) > 1 {
+		return nil, errors.New("stdio cannot be combined with other transports")
+	}
+
+	return transports, nil
+}
+
+// runNetwork serves the given network transports on one listener until ctx
+// is cancelled. All transports share mcpServer and therefore one session store.
+func runNetwork(ctx context.Context, mcpServer *server.MCPServer, thinker *thinking.SequentialThinkingServer, cfg httpConfig, transports []string) error {
+	ln, err := cfg.listen.listen()
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{TLSConfig: cfg.tls}
+	mux := http.NewServeMux()
+	shutdown := mountTransports(mux, mcpServer, httpServer, cfg, transports)
+	cfg.registerCommonHandlers(mux, httpServer, thinker)
+	httpServer.Handler = mux
+
+	return serveUntilDone(ctx, httpServer, ln, func(ctx context.Context) error {
+		// Probes see /readyz fail while open connections are still drained
+		thinker.Drain()
+		return shutdown(ctx)
+	}, cfg.deadline)
+}
+
+// mountTransports mounts the MCP endpoints of each network transport on mux
+// and returns the function that shuts them down together with httpServer
+func mountTransports(mux *http.ServeMux, mcpServer *server.MCPServer, httpServer *http.Server, cfg httpConfig, transports []string) func(context.Context) error {
+	var closers []func(context.Context) error
+	for _, name := range transports {
+		switch name {
+		case "sse":
+			sseServer := server.NewSSEServer(mcpServer,
+				server.WithHTTPServer(httpServer),
+				server.WithSSEContextFunc(cfg.requestContext),
+				// The endpoint event then points clients at the prefixed message path
+				server.WithStaticBasePath(cfg.basePath),
+			)
+			// The SSE server routes both the stream and the message endpoint itself
+			handler := cfg.cors.handler(requireAuth(cfg.auth, sseServer))
+			mux.Handle(sseServer.CompleteSsePath(), handler)
+			mux.Handle(sseServer.CompleteMessagePath(), handler)
+			closers = append(closers, sseServer.Shutdown)
+
+		case "http":
+			streamableServer := server.NewStreamableHTTPServer(mcpServer,
+				server.WithHTTPContextFunc(cfg.requestContext),
+			)
+			streams := newListenStreams()
+			mux.Handle(cfg.path("/mcp"), cfg.cors.handler(requireAuth(cfg.auth, streams.handler(streamableServer))))
+			// The streamable server does not own httpServer, so its Shutdown
+			// leaves the listener alone; the listening streams are ended here.
+			// That never blocks, so it goes first: the SSE closer waits for
+			// open connections on the shared server.
+			closers = append([]func(context.Context) error{func(ctx context.Context) error {
+				streams.close()
+				return streamableServer.Shutdown(ctx)
+			}}, closers...)
+
+		case "websocket":
+			// WebSocket has no CORS; the handshake checks Origin against the same list
+			opts := cfg.websocket
+			opts.allowedOrigins = cfg.cors.allowedOrigins
+			wsServer := newWebsocketServer(mcpServer, opts, cfg.requestContext)
+			mux.Handle(cfg.path("/ws"), requireAuth(cfg.auth, wsServer))
+			closers = append(closers, wsServer.Shutdown)
+		}
+	}
+
+	// SSE streams and WebSocket connections never end on their own, so they
+	// are closed before the listener waits for open connections
+	return shutdownAll(append(closers, httpServer.Shutdown))
+}
+
+// shutdownAll returns a function that runs every closer in order. A failing
+// closer does not stop the later ones; all errors are returned joined.
+func shutdownAll(closers []func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, closeTransport := range closers {
+			errs = append(errs, closeTransport(ctx))
+		}
+
+		return errors.Join(errs...)
+	}
+}
+
+// listenStreams tracks the GET streams on which streamable HTTP clients
+// listen for notifications. They only end when the client leaves, so
+// shutdown ends them instead of waiting for them until the deadline.
+type listenStreams struct {
+	mu      sync.Mutex
+	cancels map[*http.Request]context.CancelFunc
+	closed  bool
+}
+
+// newListenStreams creates an empty stream tracker
+func newListenStreams() *listenStreams {
+	return &listenStreams{cancels: make(map[*http.Request]context.CancelFunc)}
+}
+
+// handler passes requests to next, ending GET streams on close
+func (l *listenStreams) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		l.cancels[r] = cancel
+		l.mu.Unlock()
+		defer func() {
+			l.mu.Lock()
+			delete(l.cancels, r)
+			l.mu.Unlock()
+		}()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// close ends the open streams and refuses new ones
+func (l *listenStreams) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	for _, cancel := range l.cancels {
+		cancel()
+	}
+}
+
+// shutdownDeadline is the -shutdown-timeout of the whole shutdown. Its clock
+// starts on first use, so the HTTP shutdown and the drain of the thinking
+// server that follows share one deadline instead of getting one each.
+type shutdownDeadline struct {
+	timeout time.Duration
+
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newShutdownDeadline creates a deadline of timeout that has not started yet
+func newShutdownDeadline(timeout time.Duration) *shutdownDeadline {
+	return &shutdownDeadline{timeout: timeout}
+}
+
+// context starts the clock on the first call and returns the context that
+// expires at the deadline
+func (d *shutdownDeadline) context() context.Context {
+	d.once.Do(func() {
+		d.ctx, d.cancel = context.WithTimeout(context.Background(), d.timeout)
+	})
+
+	return d.ctx
+}
+
+// stop releases the deadline's timer
+func (d *shutdownDeadline) stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// serveUntilDone runs srv on ln until it fails or ctx is cancelled. On
+// cancellation the listener is closed and shutdown is given until deadline
+// to let in-flight requests complete.
+func serveUntilDone(ctx context.Context, srv *http.Server, ln net.Listener, shutdown func(context.Context) error, deadline *shutdownDeadline) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			// Certificates come from TLSConfig, so no files are passed here
+			errCh <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	if err := shutdown(deadline.context()); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
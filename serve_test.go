@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+func TestParseTransports(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "stdio", want: []string{"stdio"}},
+		{value: "http", want: []string{"http"}},
+		{value: "sse,http", want: []string{"sse", "http"}},
+		{value: " http , sse,http", want: []string{"http", "sse"}},
+		{value: "stdio,http", wantErr: true},
+		{value: "grpc", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTransports(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTransports() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTransports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestMCPServer registers a server configured by opts on a fresh MCP server
+func newTestMCPServer(opts ...thinking.Option) (*server.MCPServer, *thinking.SequentialThinkingServer) {
+	mcpServer := newMCPServer()
+	return mcpServer, thinking.Register(mcpServer, opts...)
+}
+
+// defaultStore returns the session store of the default tenant
+func defaultStore(t *testing.T, thinker *thinking.SequentialThinkingServer) thinking.SessionStore {
+	t.Helper()
+
+	store, err := thinker.Store(thinking.DefaultTenant)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	return store
+}
+
+func TestSSEAndHTTPShareOneStore(t *testing.T) {
+	mcpServer, thinker := newTestMCPServer()
+	httpServer := &http.Server{}
+	mux := http.NewServeMux()
+	shutdown := mountTransports(mux, mcpServer, httpServer, httpConfig{}, []string{"sse", "http"})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	defer shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sseClient, err := client.NewSSEMCPClient(ts.URL + "/sse")
+	if err != nil {
+		t.Fatalf("NewSSEMCPClient failed: %v", err)
+	}
+	defer sseClient.Close()
+	if err := sseClient.Start(ctx); err != nil {
+		t.Fatalf("Starting SSE client failed: %v", err)
+	}
+
+	httpClient, err := client.NewStreamableHttpClient(ts.URL + "/mcp")
+	if err != nil {
+		t.Fatalf("NewStreamableHttpClient failed: %v", err)
+	}
+	defer httpClient.Close()
+
+	for name, c := range map[string]*client.Client{"sse": sseClient, "http": httpClient} {
+		if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+			t.Fatalf("Initializing %s client failed: %v", name, err)
+		}
+
+		request := mcp.CallToolRequest{}
+		request.Params.Name = "sequentialthinking"
+		request.Params.Arguments = map[string]interface{}{
+			"thought":           "Thinking over " + name,
+			"nextThoughtNeeded": true,
+			"thoughtNumber":     1,
+			"totalThoughts":     2,
+		}
+		result, err := c.CallTool(ctx, request)
+		if err != nil {
+			t.Fatalf("CallTool over %s failed: %v", name, err)
+		}
+		if result.IsError {
+			t.Fatalf("CallTool over %s returned a tool error: %v", name, result.Content)
+		}
+	}
+
+	store := defaultStore(t, thinker)
+	thoughts := 0
+	for _, id := range store.List() {
+		history, _ := store.Get(id)
+		thoughts += len(history.Thoughts)
+	}
+	if thoughts != 2 {
+		t.Errorf("Expected both transports to store into one session store, found %d thoughts", thoughts)
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "/", want: ""},
+		{value: "/tools/seqthink/", want: "/tools/seqthink"},
+		{value: "tools/seqthink", want: "/tools/seqthink"},
+		{value: "/tools?x=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := normalizeBasePath(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeBasePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeBasePath() = '%s', want '%s'", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	mcpServer, thinker := newTestMCPServer()
+	cfg := httpConfig{basePath: "/tools/seqthink"}
+	mux := http.NewServeMux()
+	httpServer := &http.Server{}
+	shutdown := mountTransports(mux, mcpServer, httpServer, cfg, []string{"sse", "http"})
+	cfg.registerCommonHandlers(mux, httpServer, thinker)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	defer shutdown(context.Background())
+
+	for path, want := range map[string]int{
+		"/tools/seqthink/healthz": http.StatusOK,
+		"/tools/seqthink/version": http.StatusOK,
+		"/healthz":                http.StatusNotFound,
+		"/sse":                    http.StatusNotFound,
+	} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s: expected %d, got %d", path, want, resp.StatusCode)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The SSE client posts to whatever message endpoint the server announces,
+	// so a working call proves the announced URL carries the prefix
+	sseClient, err := client.NewSSEMCPClient(ts.URL + "/tools/seqthink/sse")
+	if err != nil {
+		t.Fatalf("NewSSEMCPClient failed: %v", err)
+	}
+	defer sseClient.Close()
+	if err := sseClient.Start(ctx); err != nil {
+		t.Fatalf("Starting SSE client failed: %v", err)
+	}
+	if _, err := sseClient.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize over prefixed SSE failed: %v", err)
+	}
+
+	httpClient, err := client.NewStreamableHttpClient(ts.URL + "/tools/seqthink/mcp")
+	if err != nil {
+		t.Fatalf("NewStreamableHttpClient failed: %v", err)
+	}
+	defer httpClient.Close()
+	if _, err := httpClient.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize over prefixed HTTP failed: %v", err)
+	}
+}
+
+func TestShutdownDeadlineIsShared(t *testing.T) {
+	deadline := newShutdownDeadline(time.Minute)
+	defer deadline.stop()
+
+	before := time.Now()
+	first, ok := deadline.context().Deadline()
+	if !ok || first.Before(before.Add(time.Minute)) {
+		t.Fatalf("Expected the clock to start on first use, got deadline %v", first)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if second, _ := deadline.context().Deadline(); !second.Equal(first) {
+		t.Errorf("Expected later users to share deadline %v, got %v", first, second)
+	}
+}
+
+func TestShutdownRunsEveryCloser(t *testing.T) {
+	var ran []string
+	closer := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+	sseErr, wsErr := errors.New("sse failed"), errors.New("websocket failed")
+	shutdown := shutdownAll([]func(context.Context) error{
+		closer("sse", sseErr),
+		closer("websocket", wsErr),
+		closer("listener", nil),
+	})
+
+	err := shutdown(context.Background())
+	if !errors.Is(err, sseErr) || !errors.Is(err, wsErr) {
+		t.Errorf("Expected both errors, got %v", err)
+	}
+	if want := []string{"sse", "websocket", "listener"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("Expected closers %v to run, got %v", want, ran)
+	}
+}
+
+func TestShutdownEndsStreamableListenStreams(t *testing.T) {
+	mcpServer, _ := newTestMCPServer()
+	httpServer := &http.Server{}
+	mux := http.NewServeMux()
+	shutdown := mountTransports(mux, mcpServer, httpServer, httpConfig{}, []string{"sse", "http"})
+	httpServer.Handler = mux
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go httpServer.Serve(ln)
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/mcp")
+	if err != nil {
+		t.Fatalf("Opening the listening stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202 for the listening stream, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		t.Errorf("Expected shutdown to end the open stream, got %v", err)
+	}
+}
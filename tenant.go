@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// withTenantHeader records the tenant named by the request header in ctx.
+// An authenticated caller may only pick a tenant this way if it is one of
+// admins; others stay in the tenant of their credentials.
+func withTenantHeader(ctx context.Context, r *http.Request, header string, admins map[string]bool) context.Context {
+	if header == "" {
+		return ctx
+	}
+	if p, ok := thinking.PrincipalFromContext(r.Context()); ok && !admins[p.Subject] {
+		return ctx
+	}
+	if id := r.Header.Get(header); id != "" {
+		return thinking.ContextWithTenant(ctx, id)
+	}
+
+	return ctx
+}
+
+// handleAdminTenants lists tenants and their usage; only admins may call it
+func handleAdminTenants(thinker *thinking.SequentialThinkingServer, admins map[string]bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := thinking.PrincipalFromContext(r.Context())
+		if !ok || !admins[p.Subject] {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(thinker.TenantUsage())
+	})
+}
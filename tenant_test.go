@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+func thoughtCall(number int, next bool) mcp.CallToolRequest {
+	return mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "sequentialthinking",
+			Arguments: map[string]interface{}{
+				"thought":           "Thinking",
+				"nextThoughtNeeded": next,
+				"thoughtNumber":     float64(number),
+				"totalThoughts":     float64(3),
+			},
+		},
+	}
+}
+
+func TestWithTenantHeader(t *testing.T) {
+	r := httptest.NewRequest("POST", "/mcp", nil)
+	r.Header.Set("X-Tenant-ID", "acme")
+	admins := map[string]bool{"root": true}
+
+	if got, _ := thinking.TenantFromContext(withTenantHeader(context.Background(), r, "X-Tenant-ID", admins)); got != "acme" {
+		t.Errorf("Expected tenant 'acme' from the header, got '%s'", got)
+	}
+	if got, _ := thinking.TenantFromContext(withTenantHeader(context.Background(), r, "", admins)); got != thinking.DefaultTenant {
+		t.Errorf("Expected the header to be ignored when disabled, got '%s'", got)
+	}
+
+	user := r.WithContext(thinking.ContextWithPrincipal(r.Context(), &thinking.Principal{Subject: "bob"}))
+	if got, _ := thinking.TenantFromContext(withTenantHeader(user.Context(), user, "X-Tenant-ID", admins)); got != thinking.DefaultTenant {
+		t.Errorf("Expected an authenticated non-admin to stay in the default tenant, got '%s'", got)
+	}
+	admin := r.WithContext(thinking.ContextWithPrincipal(r.Context(), &thinking.Principal{Subject: "root"}))
+	if got, _ := thinking.TenantFromContext(withTenantHeader(admin.Context(), admin, "X-Tenant-ID", admins)); got != "acme" {
+		t.Errorf("Expected an admin to pick tenant 'acme', got '%s'", got)
+	}
+}
+
+func TestAdminTenantsEndpoint(t *testing.T) {
+	server := thinking.NewSequentialThinkingServer()
+	acme := thinking.ContextWithPrincipal(context.Background(), &thinking.Principal{Subject: "alice", Tenant: "acme"})
+	if _, err := server.CallTool(acme, thoughtCall(1, true)); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+
+	auth, err := newTokenAuthenticator(writeTempFile(t, "tokens", "root admin-token\nalice user-token acme\n"))
+	if err != nil {
+		t.Fatalf("newTokenAuthenticator failed: %v", err)
+	}
+	handler := requireAuth(auth, handleAdminTenants(server, map[string]bool{"root": true}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestWithToken("user-token"))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-admin, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestWithToken("admin-token"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for an admin, got %d", rec.Code)
+	}
+
+	var usage []thinking.TenantUsage
+	if err := json.NewDecoder(rec.Body).Decode(&usage); err != nil {
+		t.Fatalf("Failed to decode usage: %v", err)
+	}
+	found := false
+	for _, u := range usage {
+		if u.Tenant == "acme" {
+			found = true
+			if u.Sessions != 1 || u.Thoughts != 1 || u.Calls != 1 {
+				t.Errorf("Unexpected acme usage: %+v", u)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected tenant acme in usage, got %+v", usage)
+	}
+}
@@ -10,7 +10,14 @@ fi
 
 # Запускаем unit тесты
 echo "Запуск unit тестов..."
-go test -v
+go test -v ./...
+
+# Сверяем записанные сессии с эталонными ответами
+echo ""
+echo "Воспроизведение записанных сессий..."
+for transcript in testdata/replay/*.jsonl; do
+    ./sequentialthinking-server replay -golden "${transcript%.jsonl}.golden" "$transcript" || exit 1
+done
 
 # Создаем тестовые MCP запросы
 echo ""
@@ -0,0 +1,229 @@
+// Package thinkclient is a typed Go client for the sequentialthinking MCP
+// server. It works over any mcp-go client transport: stdio, SSE, streamable
+// HTTP or in-process.
+//
+//	mcpClient, _ := client.NewStreamableHttpClient("http://localhost:8080/mcp")
+//	_ = mcpClient.Start(ctx)
+//	_, _ = mcpClient.Initialize(ctx, mcp.InitializeRequest{})
+//
+//	thinker := thinkclient.New(mcpClient)
+//	resp, err := thinker.Think(ctx, thinkclient.ThoughtRequest{
+//		Thought:           "Break the problem down",
+//		ThoughtNumber:     1,
+//		TotalThoughts:     3,
+//		NextThoughtNeeded: true,
+//	})
+package thinkclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ToolName is the name the server registers its tool under
+const ToolName = "sequentialthinking"
+
+// sessionURI is the resource URI prefix of sessions
+const sessionURI = "sequentialthinking://sessions/"
+
+// ThoughtRequest is one thought sent to the server
+type ThoughtRequest struct {
+	Thought           string `json:"thought"`
+	NextThoughtNeeded bool   `json:"nextThoughtNeeded"`
+	ThoughtNumber     int    `json:"thoughtNumber"`
+	TotalThoughts     int    `json:"totalThoughts"`
+	IsRevision        bool   `json:"isRevision,omitempty"`
+	RevisesThought    int    `json:"revisesThought,omitempty"`
+	BranchFromThought int    `json:"branchFromThought,omitempty"`
+	BranchID          string `json:"branchId,omitempty"`
+	NeedsMoreThoughts bool   `json:"needsMoreThoughts,omitempty"`
+}
+
+// ThoughtResponse is the server's answer to a stored thought
+type ThoughtResponse struct {
+	// SessionID names the session the thought was stored in
+	SessionID         string   `json:"sessionId"`
+	ThoughtNumber     int      `json:"thoughtNumber"`
+	TotalThoughts     int      `json:"totalThoughts"`
+	NextThoughtNeeded bool     `json:"nextThoughtNeeded"`
+	Branches          []string `json:"branches"`
+	// ThoughtHistoryLength counts the thoughts of the session so far
+	ThoughtHistoryLength int `json:"thoughtHistoryLength"`
+	// Text is the formatted response shown to models
+	Text string `json:"-"`
+}
+
+// History is a session with all its thoughts
+type History struct {
+	ID        string           `json:"id"`
+	Thoughts  []ThoughtRequest `json:"thoughts"`
+	Branches  map[string][]int `json:"branches,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// Format is an export format
+type Format string
+
+// Export formats
+const (
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+)
+
+// ToolError is a tool call the server refused, such as a rate-limited one
+type ToolError struct {
+	Message string
+	// RetryAfter is how long to wait before retrying; zero if unknown
+	RetryAfter time.Duration
+}
+
+func (e *ToolError) Error() string {
+	return e.Message
+}
+
+// ErrNoMetadata is returned when a response lacks the structured result,
+// which happens with servers that predate it
+var ErrNoMetadata = errors.New("response carries no structured result")
+
+// Client calls the sequentialthinking server through an mcp-go client
+type Client struct {
+	mcp *client.Client
+}
+
+// New wraps c, which the caller must have started and initialized
+func New(c *client.Client) *Client {
+	return &Client{mcp: c}
+}
+
+// Think stores one thought. Validation failures are returned as errors, and
+// refusals such as rate limiting as *ToolError.
+func (c *Client) Think(ctx context.Context, req ThoughtRequest) (*ThoughtResponse, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var args map[string]any
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil, err
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = ToolName
+	request.Params.Arguments = args
+	result, err := c.mcp.CallTool(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	text := resultText(result)
+	if result.IsError {
+		var meta struct {
+			RetryAfterSeconds float64 `json:"retryAfterSeconds"`
+		}
+		// A refusal without metadata still reports its message
+		_ = decodeMeta(result.Meta, &meta)
+		return nil, &ToolError{
+			Message:    text,
+			RetryAfter: time.Duration(meta.RetryAfterSeconds * float64(time.Second)),
+		}
+	}
+
+	if result.Meta == nil {
+		return nil, ErrNoMetadata
+	}
+	resp := &ThoughtResponse{Text: text}
+	if err := decodeMeta(result.Meta, resp); err != nil {
+		return nil, fmt.Errorf("parsing structured result: %w", err)
+	}
+
+	return resp, nil
+}
+
+// decodeMeta decodes a result's _meta into v. In-process clients get the
+// server's values as they are, so they go through JSON like remote ones.
+func decodeMeta(meta map[string]any, v any) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// Revise stores req as a revision of thought revises
+func (c *Client) Revise(ctx context.Context, revises int, req ThoughtRequest) (*ThoughtResponse, error) {
+	req.IsRevision = true
+	req.RevisesThought = revises
+
+	return c.Think(ctx, req)
+}
+
+// Branch stores req on branch branchID, which starts from thought fromThought
+func (c *Client) Branch(ctx context.Context, fromThought int, branchID string, req ThoughtRequest) (*ThoughtResponse, error) {
+	req.BranchFromThought = fromThought
+	req.BranchID = branchID
+
+	return c.Think(ctx, req)
+}
+
+// History returns a session of the caller's tenant with all its thoughts
+func (c *Client) History(ctx context.Context, sessionID string) (*History, error) {
+	data, err := c.read(ctx, sessionURI+url.PathEscape(sessionID))
+	if err != nil {
+		return nil, err
+	}
+
+	var history History
+	if err := json.Unmarshal([]byte(data), &history); err != nil {
+		return nil, fmt.Errorf("parsing session: %w", err)
+	}
+
+	return &history, nil
+}
+
+// Export returns a session rendered in format
+func (c *Client) Export(ctx context.Context, sessionID string, format Format) ([]byte, error) {
+	data, err := c.read(ctx, sessionURI+url.PathEscape(sessionID)+"/export?format="+url.QueryEscape(string(format)))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(data), nil
+}
+
+// read returns the text of the resource at uri
+func (c *Client) read(ctx context.Context, uri string) (string, error) {
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	result, err := c.mcp.ReadResource(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	for _, content := range result.Contents {
+		if text, ok := mcp.AsTextResourceContents(content); ok {
+			return text.Text, nil
+		}
+	}
+
+	return "", fmt.Errorf("resource %s has no text", uri)
+}
+
+// resultText joins the text content of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	var text string
+	for _, content := range result.Content {
+		if t, ok := mcp.AsTextContent(content); ok {
+			text += t.Text
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,157 @@
+package thinkclient_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/ad/sequentialthinking/thinkclient"
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// startClient connects an in-process client, calling as ctx's caller, to a
+// server configured by opts. A fixed clock keeps every call in one session.
+func startClient(ctx context.Context, t *testing.T, opts ...thinking.Option) *thinkclient.Client {
+	t.Helper()
+
+	mcpServer := server.NewMCPServer("sequentialthinking", "test")
+	thinking.Register(mcpServer, append([]thinking.Option{
+		thinking.WithLogger(slog.New(slog.DiscardHandler)),
+		thinking.WithClock(func() time.Time { return time.Unix(1700000000, 0) }),
+	}, opts...)...)
+	c, err := client.NewInProcessClient(mcpServer)
+	if err != nil {
+		t.Fatalf("NewInProcessClient failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	return thinkclient.New(c)
+}
+
+func TestThinkClient(t *testing.T) {
+	c := startClient(t.Context(), t)
+	ctx := t.Context()
+
+	first, err := c.Think(ctx, thinkclient.ThoughtRequest{Thought: "Outline", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true})
+	if err != nil {
+		t.Fatalf("Think failed: %v", err)
+	}
+	if first.SessionID == "" || first.ThoughtNumber != 1 || first.TotalThoughts != 3 || !first.NextThoughtNeeded || first.ThoughtHistoryLength != 1 {
+		t.Errorf("Unexpected response: %+v", first)
+	}
+	if !strings.Contains(first.Text, "Outline") {
+		t.Errorf("Text does not show the thought: %q", first.Text)
+	}
+
+	if _, err := c.Think(ctx, thinkclient.ThoughtRequest{Thought: "Draft", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true}); err != nil {
+		t.Fatalf("Think failed: %v", err)
+	}
+	revised, err := c.Revise(ctx, 2, thinkclient.ThoughtRequest{Thought: "Better draft", ThoughtNumber: 3, TotalThoughts: 3, NextThoughtNeeded: true})
+	if err != nil {
+		t.Fatalf("Revise failed: %v", err)
+	}
+	if revised.ThoughtHistoryLength != 3 {
+		t.Errorf("Expected 3 thoughts after the revision, got %d", revised.ThoughtHistoryLength)
+	}
+	branched, err := c.Branch(ctx, 1, "alt", thinkclient.ThoughtRequest{Thought: "Another way", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: false})
+	if err != nil {
+		t.Fatalf("Branch failed: %v", err)
+	}
+	if !slices.Equal(branched.Branches, []string{"alt"}) || branched.SessionID != first.SessionID {
+		t.Errorf("Unexpected branch response: %+v", branched)
+	}
+
+	history, err := c.History(ctx, first.SessionID)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if history.ID != first.SessionID || len(history.Thoughts) != 4 || history.CreatedAt.IsZero() {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+	if rev := history.Thoughts[2]; !rev.IsRevision || rev.RevisesThought != 2 {
+		t.Errorf("Revision not stored as such: %+v", rev)
+	}
+	if branch := history.Thoughts[3]; branch.BranchID != "alt" || branch.BranchFromThought != 1 {
+		t.Errorf("Branch not stored as such: %+v", branch)
+	}
+
+	markdown, err := c.Export(ctx, first.SessionID, thinkclient.FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(string(markdown), "Another way") {
+		t.Errorf("Export misses a thought:\n%s", markdown)
+	}
+	if _, err := c.Export(ctx, first.SessionID, "pdf"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+	if _, err := c.History(ctx, "session_0"); err == nil {
+		t.Error("Expected an error for an unknown session")
+	}
+}
+
+func TestThinkClientInvalidThought(t *testing.T) {
+	c := startClient(t.Context(), t)
+
+	_, err := c.Think(t.Context(), thinkclient.ThoughtRequest{ThoughtNumber: 1, TotalThoughts: 1})
+	if err == nil {
+		t.Fatal("Expected an error for an empty thought")
+	}
+	var toolErr *thinkclient.ToolError
+	if errors.As(err, &toolErr) {
+		t.Errorf("Validation failure reported as a tool error: %v", err)
+	}
+}
+
+func TestThinkClientRateLimited(t *testing.T) {
+	c := startClient(t.Context(), t, thinking.WithRateLimit(thinking.RateLimitConfig{DailyQuota: 1}))
+	req := thinkclient.ThoughtRequest{Thought: "Once", ThoughtNumber: 1, TotalThoughts: 2, NextThoughtNeeded: true}
+
+	if _, err := c.Think(t.Context(), req); err != nil {
+		t.Fatalf("First call failed: %v", err)
+	}
+	_, err := c.Think(t.Context(), req)
+	var toolErr *thinkclient.ToolError
+	if !errors.As(err, &toolErr) {
+		t.Fatalf("Expected a ToolError, got %v", err)
+	}
+	if toolErr.RetryAfter <= 0 || toolErr.Message == "" {
+		t.Errorf("Unexpected tool error: %+v", toolErr)
+	}
+}
+
+func TestThinkClientScopedSession(t *testing.T) {
+	ctx := thinking.ContextWithPrincipal(t.Context(), &thinking.Principal{Subject: "alice"})
+	scoped := startClient(ctx, t)
+
+	resp, err := scoped.Think(ctx, thinkclient.ThoughtRequest{Thought: "Mine", ThoughtNumber: 1, TotalThoughts: 1})
+	if err != nil {
+		t.Fatalf("Think failed: %v", err)
+	}
+	if !strings.HasPrefix(resp.SessionID, "alice/") {
+		t.Fatalf("Expected a session scoped to alice, got %q", resp.SessionID)
+	}
+
+	// The slash in the ID survives the resource URI
+	history, err := scoped.History(ctx, resp.SessionID)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if history.ID != resp.SessionID || len(history.Thoughts) != 1 {
+		t.Errorf("Unexpected history: %+v", history)
+	}
+}
@@ -0,0 +1,86 @@
+package thinkclient_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/ad/sequentialthinking/thinkclient"
+)
+
+func Example() {
+	ctx := context.Background()
+
+	// Any mcp-go client works; this one starts the server over stdio
+	mcpClient, err := client.NewStdioMCPClient("./sequentialthinking-server", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer mcpClient.Close()
+	if _, err := mcpClient.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		log.Fatal(err)
+	}
+
+	thinker := thinkclient.New(mcpClient)
+	resp, err := thinker.Think(ctx, thinkclient.ThoughtRequest{
+		Thought:           "List what the migration has to move",
+		ThoughtNumber:     1,
+		TotalThoughts:     3,
+		NextThoughtNeeded: true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(resp.SessionID, resp.ThoughtHistoryLength)
+}
+
+func ExampleClient_Branch() {
+	ctx := context.Background()
+
+	mcpClient, err := client.NewStreamableHttpClient("http://localhost:8080/mcp")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer mcpClient.Close()
+	if _, err := mcpClient.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		log.Fatal(err)
+	}
+	thinker := thinkclient.New(mcpClient)
+
+	// Explore an alternative to thought 1 on its own branch
+	resp, err := thinker.Branch(ctx, 1, "replicate", thinkclient.ThoughtRequest{
+		Thought:       "Replicate, then cut over",
+		ThoughtNumber: 2,
+		TotalThoughts: 3,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(resp.Branches)
+}
+
+func ExampleClient_Export() {
+	ctx := context.Background()
+
+	mcpClient, err := client.NewSSEMCPClient("http://localhost:8080/sse")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer mcpClient.Close()
+	if err := mcpClient.Start(ctx); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := mcpClient.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		log.Fatal(err)
+	}
+	thinker := thinkclient.New(mcpClient)
+
+	markdown, err := thinker.Export(ctx, "session_1700000000", thinkclient.FormatMarkdown)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s", markdown)
+}
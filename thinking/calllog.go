@@ -0,0 +1,302 @@
+package thinking
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// CallRecord is one entry of the call log: a tool call as the client made it
+// and what the server did with it
+type CallRecord struct {
+	// Seq numbers records in the order they were written, starting at 1
+	Seq    int64     `json:"seq"`
+	Time   time.Time `json:"time"`
+	Tenant string    `json:"tenant,omitempty"`
+	Client string    `json:"client,omitempty"`
+	Tool   string    `json:"tool"`
+	// Arguments are the raw tool arguments as received, or the thought as
+	// handled when middleware rewrote it
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	// SessionID and Thought are set once the arguments have been decoded
+	SessionID string          `json:"sessionId,omitempty"`
+	Thought   *ThoughtRequest `json:"thought,omitempty"`
+	Outcome   string          `json:"outcome"`
+	// Reason is the validation failure reason of rejected thoughts
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// DeleteSessionRecord is the Tool of call log records that note a deleted
+// session rather than a tool call
+const DeleteSessionRecord = "delete_session"
+
+// CallLog is an append-only record of every tool call
+type CallLog interface {
+	// Append assigns rec the next sequence number and records it
+	Append(rec CallRecord) error
+	// Close flushes the log; it must not be used afterwards
+	Close() error
+}
+
+// errCallLogClosed is returned when appending to a closed call log
+var errCallLogClosed = errors.New("call log is closed")
+
+// fileCallLog writes one JSON record per line to an append-only file
+type fileCallLog struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  int64
+}
+
+// OpenCallLog opens the log at path, creating it if needed, and returns the
+// records it already holds so the sessions can be rebuilt from them
+func OpenCallLog(path string) (CallLog, []CallRecord, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening call log: %w", err)
+	}
+	records, intact, err := scanCallLog(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("reading call log %s: %w", path, err)
+	}
+	// Drop a torn final write so new records start on a line of their own
+	if info, err := file.Stat(); err == nil && info.Size() > intact {
+		if err := file.Truncate(intact); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("truncating torn call log record: %w", err)
+		}
+	}
+
+	l := &fileCallLog{file: file}
+	if n := len(records); n > 0 {
+		l.seq = records[n-1].Seq
+	}
+
+	return l, records, nil
+}
+
+// Append writes rec as one line; records are never rewritten
+func (l *fileCallLog) Append(rec CallRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return errCallLogClosed
+	}
+
+	rec.Seq = l.seq + 1
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding call record: %w", err)
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing call log: %w", err)
+	}
+	l.seq = rec.Seq
+
+	return nil
+}
+
+// Close syncs the file to disk and closes it
+func (l *fileCallLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	file := l.file
+	l.file = nil
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("syncing call log: %w", err)
+	}
+
+	return file.Close()
+}
+
+// readCallLog parses a JSON Lines call log. Append writes every record with
+// its newline in one write, so a final line without a newline is a write cut
+// short by a crash and is ignored.
+func readCallLog(r io.Reader) ([]CallRecord, error) {
+	records, _, err := scanCallLog(r)
+	return records, err
+}
+
+// scanCallLog parses a call log and also returns the length of the intact
+// prefix, which excludes a torn final line
+func scanCallLog(r io.Reader) ([]CallRecord, int64, error) {
+	var records []CallRecord
+	var intact int64
+	reader := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return records, intact, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		intact += int64(len(line))
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			var rec CallRecord
+			if err := json.Unmarshal(trimmed, &rec); err != nil {
+				return nil, 0, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			records = append(records, rec)
+		}
+	}
+}
+
+// rebuildSessions replays the successful calls and deletions of a call log
+// and returns every session left, keyed by tenant and session ID. Sessions
+// start at the time of their first recorded thought.
+func rebuildSessions(records []CallRecord) map[string]map[string]*ThoughtHistory {
+	tenants := make(map[string]map[string]*ThoughtHistory)
+	for _, rec := range records {
+		if rec.Outcome != OutcomeSuccess || rec.SessionID == "" {
+			continue
+		}
+		tenantID := rec.Tenant
+		if tenantID == "" {
+			tenantID = DefaultTenant
+		}
+		if rec.Tool == DeleteSessionRecord {
+			delete(tenants[tenantID], rec.SessionID)
+			continue
+		}
+		if rec.Thought == nil {
+			continue
+		}
+		sessions := tenants[tenantID]
+		if sessions == nil {
+			sessions = make(map[string]*ThoughtHistory)
+			tenants[tenantID] = sessions
+		}
+
+		history := sessions[rec.SessionID]
+		if history == nil {
+			history = &ThoughtHistory{
+				Thoughts:  []ThoughtRequest{},
+				Branches:  make(map[string][]int),
+				CreatedAt: rec.Time,
+			}
+			sessions[rec.SessionID] = history
+		}
+		history.Thoughts = append(history.Thoughts, *rec.Thought)
+		if rec.Thought.BranchID != "" {
+			history.Branches[rec.Thought.BranchID] = append(history.Branches[rec.Thought.BranchID], rec.Thought.ThoughtNumber)
+		}
+	}
+
+	return tenants
+}
+
+// RestoreSessions loads the sessions rebuilt from records into the tenant
+// stores. It is meant to run before the server takes tool calls.
+func (s *SequentialThinkingServer) RestoreSessions(records []CallRecord) error {
+	rebuilt := rebuildSessions(records)
+	tenantIDs := make([]string, 0, len(rebuilt))
+	for id := range rebuilt {
+		tenantIDs = append(tenantIDs, id)
+	}
+	sort.Strings(tenantIDs)
+
+	for _, tenantID := range tenantIDs {
+		t := s.tenants.create(tenantID)
+		for sessionID, history := range rebuilt[tenantID] {
+			if err := restoreSession(t.store, sessionID, history); err != nil {
+				return fmt.Errorf("restoring session %s of tenant %s: %w", sessionID, tenantID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordCall appends one call to the call log, if there is one. A failing
+// log does not fail the call; it is reported in the server log instead.
+func (s *SequentialThinkingServer) recordCall(ctx context.Context, now time.Time, request mcp.CallToolRequest, tenantID, sessionID string, req *ThoughtRequest, outcome string, result *mcp.CallToolResult, err error) {
+	if s.callLog == nil {
+		return
+	}
+
+	rec := CallRecord{
+		Time:      now,
+		Tenant:    tenantID,
+		Client:    clientKey(ctx),
+		Tool:      request.Params.Name,
+		SessionID: sessionID,
+		Outcome:   outcome,
+	}
+	if args, marshalErr := json.Marshal(request.Params.Arguments); marshalErr == nil {
+		rec.Arguments = args
+	}
+	if sessionID != "" {
+		thought := *req
+		rec.Thought = &thought
+	}
+	var verr *validationError
+	if errors.As(err, &verr) {
+		rec.Reason = verr.Reason
+	}
+	switch {
+	case err != nil:
+		rec.Error = err.Error()
+	case result != nil && result.IsError:
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				rec.Error = text.Text
+				break
+			}
+		}
+	}
+
+	s.appendRecord(rec)
+}
+
+// recordDeletion notes a deleted session in the call log, if there is one,
+// so RestoreSessions does not bring it back
+func (s *SequentialThinkingServer) recordDeletion(ctx context.Context, tenantID, sessionID string) {
+	if s.callLog == nil {
+		return
+	}
+
+	s.appendRecord(CallRecord{
+		Time:      s.now(),
+		Tenant:    tenantID,
+		Client:    clientKey(ctx),
+		Tool:      DeleteSessionRecord,
+		SessionID: sessionID,
+		Outcome:   OutcomeSuccess,
+	})
+}
+
+// appendRecord appends rec unless Shutdown has already closed the log.
+// Admitted calls hold their in-flight slot until they are recorded, so only
+// calls refused after the log closed, or still running when the shutdown
+// deadline expired, go unrecorded.
+func (s *SequentialThinkingServer) appendRecord(rec CallRecord) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.logClosed {
+		return
+	}
+	if err := s.callLog.Append(rec); err != nil {
+		s.logger.Error("call log append failed", "error", err)
+	}
+}
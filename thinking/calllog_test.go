@@ -0,0 +1,296 @@
+package thinking
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCallLogRecordsEveryCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl")
+	callLog, records, err := OpenCallLog(path)
+	if err != nil || len(records) != 0 {
+		t.Fatalf("OpenCallLog() = %v, %v", records, err)
+	}
+	thinker := NewSequentialThinkingServer(
+		WithCallLog(callLog),
+		WithRateLimit(RateLimitConfig{Rate: 0.001, Burst: 3}),
+	)
+
+	calls := []mcp.CallToolRequest{
+		thoughtCall(1, true),
+		{Params: mcp.CallToolParams{Name: "sequentialthinking", Arguments: map[string]interface{}{
+			"thought": "", "nextThoughtNeeded": true, "thoughtNumber": float64(2), "totalThoughts": float64(2),
+		}}},
+		{Params: mcp.CallToolParams{Name: "otherTool"}},
+		thoughtCall(2, false),
+		thoughtCall(3, false),
+	}
+	for _, call := range calls {
+		_, _ = thinker.CallTool(context.Background(), call)
+	}
+	if err := thinker.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if records, err = readCallLog(file); err != nil {
+		t.Fatalf("ReadCallLog failed: %v", err)
+	}
+
+	wantOutcomes := []string{OutcomeSuccess, OutcomeValidationError, OutcomeUnknownTool, OutcomeSuccess, OutcomeRateLimited}
+	if len(records) != len(wantOutcomes) {
+		t.Fatalf("Expected %d records, got %d", len(wantOutcomes), len(records))
+	}
+	for i, rec := range records {
+		if rec.Seq != int64(i+1) || rec.Outcome != wantOutcomes[i] {
+			t.Errorf("Record %d: seq %d outcome %s, want seq %d outcome %s", i, rec.Seq, rec.Outcome, i+1, wantOutcomes[i])
+		}
+	}
+
+	rejected := records[1]
+	if rejected.Reason != "empty_thought" || !strings.Contains(rejected.Error, "thought cannot be empty") {
+		t.Errorf("Rejected call lacks its validation error: %+v", rejected)
+	}
+	if rejected.Thought == nil || rejected.Thought.ThoughtNumber != 2 || !strings.Contains(string(rejected.Arguments), `"thoughtNumber":2`) {
+		t.Errorf("Rejected call lacks its arguments: %+v", rejected)
+	}
+	if records[2].Tool != "otherTool" || records[2].Tenant != "" {
+		t.Errorf("Unexpected unknown tool record: %+v", records[2])
+	}
+	if !strings.Contains(records[4].Error, "rate limit exceeded") {
+		t.Errorf("Rate limited call lacks its error: %+v", records[4])
+	}
+}
+
+func TestRestoreSessionsFromCallLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl")
+	callLog, _, err := OpenCallLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := NewSequentialThinkingServer(WithCallLog(callLog))
+	acme := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice", Tenant: "acme"})
+	calls := []map[string]interface{}{
+		{"thought": "Start", "nextThoughtNeeded": true, "thoughtNumber": float64(1), "totalThoughts": float64(3)},
+		{"thought": "", "nextThoughtNeeded": true, "thoughtNumber": float64(2), "totalThoughts": float64(3)},
+		{"thought": "Alternative", "nextThoughtNeeded": true, "thoughtNumber": float64(2), "totalThoughts": float64(3), "branchId": "alt", "branchFromThought": float64(1)},
+		{"thought": "Rethink", "nextThoughtNeeded": false, "thoughtNumber": float64(3), "totalThoughts": float64(3), "isRevision": true, "revisesThought": float64(1)},
+	}
+	for _, ctx := range []context.Context{context.Background(), acme} {
+		for _, args := range calls {
+			request := thoughtCall(1, true)
+			request.Params.Arguments = args
+			_, _ = original.CallTool(ctx, request)
+		}
+	}
+	if err := callLog.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening the log rebuilds the same sessions in a fresh server
+	reopened, records, err := OpenCallLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	restored := NewSequentialThinkingServer(WithCallLog(reopened))
+	if err := restored.RestoreSessions(records); err != nil {
+		t.Fatalf("RestoreSessions failed: %v", err)
+	}
+
+	for _, tenantID := range []string{DefaultTenant, "acme"} {
+		want, got := original.tenants.create(tenantID).store, restored.tenants.create(tenantID).store
+		if !reflect.DeepEqual(got.List(), want.List()) || len(want.List()) == 0 {
+			t.Fatalf("Tenant %s: restored sessions %v, want %v", tenantID, got.List(), want.List())
+		}
+		for _, id := range want.List() {
+			wantHistory, _ := want.Get(id)
+			gotHistory, _ := got.Get(id)
+			if !reflect.DeepEqual(gotHistory.Thoughts, wantHistory.Thoughts) || !reflect.DeepEqual(gotHistory.Branches, wantHistory.Branches) {
+				t.Errorf("Tenant %s session %s: restored %+v, want %+v", tenantID, id, gotHistory, wantHistory)
+			}
+		}
+	}
+
+	// New records continue the sequence
+	if _, err := restored.CallTool(context.Background(), thoughtCall(1, false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file, _ := os.Open(path)
+	defer file.Close()
+	all, err := readCallLog(file)
+	if err != nil || len(all) != 9 || all[8].Seq != 9 {
+		t.Errorf("Expected 9 sequential records after reopening, got %d (err %v)", len(all), err)
+	}
+}
+
+func TestRestoreSessionsSkipsDeletedSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl")
+	callLog, _, err := OpenCallLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	original := NewSequentialThinkingServer(
+		WithCallLog(callLog),
+		WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))),
+	)
+	acme := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice", Tenant: "acme"})
+	for _, ctx := range []context.Context{context.Background(), acme} {
+		if _, err := original.CallTool(ctx, thoughtCall(1, true)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleted := original.tenants.create("acme").store.List()[0]
+	if err := original.DeleteSession(acme, deleted); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	if err := original.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	// Calls refused after shutdown are not recorded, nor reported as failing
+	// to reach the closed log
+	if _, err := original.CallTool(context.Background(), thoughtCall(2, false)); !errors.Is(err, errShuttingDown) {
+		t.Fatalf("Expected errShuttingDown, got %v", err)
+	}
+	if strings.Contains(logs.String(), "call log append failed") {
+		t.Errorf("Refused call was appended to the closed log:\n%s", logs.String())
+	}
+
+	reopened, records, err := OpenCallLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if len(records) != 3 || records[2].Tool != DeleteSessionRecord || records[2].SessionID != deleted {
+		t.Fatalf("Expected two calls and a deletion, got %+v", records)
+	}
+	restored := NewSequentialThinkingServer()
+	if err := restored.RestoreSessions(records); err != nil {
+		t.Fatalf("RestoreSessions failed: %v", err)
+	}
+	if ids := restored.tenants.create("acme").store.List(); len(ids) != 0 {
+		t.Errorf("Deleted session came back: %v", ids)
+	}
+	if ids := restored.tenants.create(DefaultTenant).store.List(); len(ids) != 1 {
+		t.Errorf("Expected the default tenant's session, got %v", ids)
+	}
+}
+
+// failingCallLog is a CallLog that cannot be closed
+type failingCallLog struct{}
+
+func (failingCallLog) Append(CallRecord) error { return nil }
+func (failingCallLog) Close() error            { return errors.New("disk full") }
+
+func TestShutdownFlushesStoresWhenCallLogFails(t *testing.T) {
+	thinker := NewSequentialThinkingServer(WithCallLog(failingCallLog{}))
+	store := thinker.tenants.create(DefaultTenant).store
+
+	err := thinker.Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "closing call log: disk full") {
+		t.Errorf("Expected the call log error, got %v", err)
+	}
+	if err := store.Ping(); !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("Expected the store to be closed, got %v", err)
+	}
+}
+
+func TestOpenCallLogDropsTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl")
+	content := `{"seq":1,"tool":"sequentialthinking","outcome":"success"}` + "\n" + `{"seq":2,"tool":"sequ`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	callLog, records, err := OpenCallLog(path)
+	if err != nil {
+		t.Fatalf("OpenCallLog failed: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected the torn record to be skipped, got %d records", len(records))
+	}
+	if err := callLog.Append(CallRecord{Tool: "sequentialthinking", Outcome: OutcomeSuccess}); err != nil {
+		t.Fatal(err)
+	}
+	callLog.Close()
+
+	data, _ := os.ReadFile(path)
+	records, err = readCallLog(strings.NewReader(string(data)))
+	if err != nil || len(records) != 2 || records[1].Seq != 2 {
+		t.Errorf("Expected the new record to replace the torn one, got %s (err %v)", data, err)
+	}
+
+	if _, err := readCallLog(strings.NewReader("not json\n")); err == nil {
+		t.Error("Expected a corrupt complete line to be an error")
+	}
+}
+
+// slowHandler is a slog.Handler that takes its time, widening the window
+// between a call finishing and being recorded
+type slowHandler struct{ slog.Handler }
+
+func (h slowHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h slowHandler) Handle(context.Context, slog.Record) error {
+	time.Sleep(50 * time.Millisecond)
+	return nil
+}
+
+func TestShutdownRecordsInFlightCalls(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	block := func(next Handler) Handler {
+		return func(ctx context.Context, call *ToolCall) (*mcp.CallToolResult, error) {
+			close(started)
+			<-release
+			return next(ctx, call)
+		}
+	}
+	callLog := &recordingCallLog{}
+	thinker := NewSequentialThinkingServer(
+		WithMiddleware(block),
+		WithCallLog(callLog),
+		WithLogger(slog.New(slowHandler{slog.DiscardHandler})),
+	)
+
+	called := make(chan error, 1)
+	go func() {
+		_, err := thinker.CallTool(context.Background(), thoughtCall(1, true))
+		called <- err
+	}()
+	<-started
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- thinker.Shutdown(context.Background()) }()
+	for thinker.Ready() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	if err := <-called; err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if len(callLog.records) != 1 || callLog.records[0].Outcome != OutcomeSuccess {
+		t.Errorf("Expected the in-flight call to be recorded, got %+v", callLog.records)
+	}
+}
@@ -0,0 +1,102 @@
+package thinking
+
+import (
+	"sync"
+	"time"
+)
+
+// Session event types, published after a thought has been stored
+const (
+	EventSessionCreated   = "session.created"
+	EventThoughtAppended  = "thought.appended"
+	EventBranchCreated    = "branch.created"
+	EventSessionCompleted = "session.completed"
+)
+
+// SessionEvent describes one change to a session
+type SessionEvent struct {
+	Type      string          `json:"type"`
+	Tenant    string          `json:"tenant"`
+	SessionID string          `json:"sessionId"`
+	Thought   *ThoughtRequest `json:"thought,omitempty"`
+	Time      time.Time       `json:"time"`
+}
+
+// sessionEvents derives the events caused by storing req; history is the
+// session after the append
+func sessionEvents(tenant, sessionID string, req *ThoughtRequest, history *ThoughtHistory, now time.Time) []SessionEvent {
+	event := func(typ string) SessionEvent {
+		return SessionEvent{Type: typ, Tenant: tenant, SessionID: sessionID, Thought: req, Time: now}
+	}
+
+	var events []SessionEvent
+	if len(history.Thoughts) == 1 {
+		events = append(events, event(EventSessionCreated))
+	}
+	if req.BranchID != "" && len(history.Branches[req.BranchID]) == 1 {
+		events = append(events, event(EventBranchCreated))
+	}
+	events = append(events, event(EventThoughtAppended))
+	if !req.NextThoughtNeeded {
+		events = append(events, event(EventSessionCompleted))
+	}
+
+	return events
+}
+
+// eventBus fans session events out to subscribers. Publishing never blocks:
+// a subscriber whose buffer is full misses events.
+type eventBus struct {
+	mu          sync.RWMutex
+	subscribers map[chan SessionEvent]struct{}
+	// dropped, if set, is called for every event a subscriber misses
+	dropped func()
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[chan SessionEvent]struct{})}
+}
+
+// subscribe returns a channel receiving every event published from now on
+// and the function that unsubscribes it
+func (b *eventBus) subscribe(buffer int) (<-chan SessionEvent, func()) {
+	ch := make(chan SessionEvent, buffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// publish delivers events to every subscriber that has room for them
+func (b *eventBus) publish(events ...SessionEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+				if b.dropped != nil {
+					b.dropped()
+				}
+			}
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every session event published from
+// now on and the function that unsubscribes it. Events are dropped while the
+// channel's buffer is full.
+func (s *SequentialThinkingServer) Subscribe(buffer int) (<-chan SessionEvent, func()) {
+	return s.events.subscribe(buffer)
+}
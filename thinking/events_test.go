@@ -0,0 +1,89 @@
+package thinking
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessionEvents(t *testing.T) {
+	now := time.Now()
+	types := func(events []SessionEvent) []string {
+		var out []string
+		for _, e := range events {
+			out = append(out, e.Type)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		req     ThoughtRequest
+		history ThoughtHistory
+		want    []string
+	}{
+		{
+			name:    "first thought",
+			req:     ThoughtRequest{ThoughtNumber: 1, NextThoughtNeeded: true},
+			history: ThoughtHistory{Thoughts: make([]ThoughtRequest, 1)},
+			want:    []string{EventSessionCreated, EventThoughtAppended},
+		},
+		{
+			name:    "new branch",
+			req:     ThoughtRequest{ThoughtNumber: 2, NextThoughtNeeded: true, BranchID: "alt", BranchFromThought: 1},
+			history: ThoughtHistory{Thoughts: make([]ThoughtRequest, 2), Branches: map[string][]int{"alt": {2}}},
+			want:    []string{EventBranchCreated, EventThoughtAppended},
+		},
+		{
+			name:    "existing branch",
+			req:     ThoughtRequest{ThoughtNumber: 3, NextThoughtNeeded: true, BranchID: "alt", BranchFromThought: 1},
+			history: ThoughtHistory{Thoughts: make([]ThoughtRequest, 3), Branches: map[string][]int{"alt": {2, 3}}},
+			want:    []string{EventThoughtAppended},
+		},
+		{
+			name:    "final thought",
+			req:     ThoughtRequest{ThoughtNumber: 3},
+			history: ThoughtHistory{Thoughts: make([]ThoughtRequest, 3)},
+			want:    []string{EventThoughtAppended, EventSessionCompleted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := sessionEvents("acme", "s1", &tt.req, &tt.history, now)
+			if got := types(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sessionEvents() = %v, want %v", got, tt.want)
+			}
+			for _, e := range events {
+				if e.Tenant != "acme" || e.SessionID != "s1" || !e.Time.Equal(now) {
+					t.Errorf("Unexpected event fields: %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestEventBusDropsForSlowSubscribers(t *testing.T) {
+	bus := newEventBus()
+	dropped := 0
+	bus.dropped = func() { dropped++ }
+	events, unsubscribe := bus.subscribe(1)
+
+	// Publishing must not block even though the buffer only holds one event
+	bus.publish(SessionEvent{Type: EventSessionCreated}, SessionEvent{Type: EventThoughtAppended})
+	if e := <-events; e.Type != EventSessionCreated {
+		t.Errorf("Expected the first event, got %s", e.Type)
+	}
+	if dropped != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", dropped)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	bus.publish(SessionEvent{Type: EventThoughtAppended})
+	select {
+	case e := <-events:
+		t.Errorf("Received %s after unsubscribing", e.Type)
+	default:
+	}
+}
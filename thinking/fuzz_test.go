@@ -0,0 +1,245 @@
+package thinking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// validationReasons are all reasons validateThoughtRequest may report
+var validationReasons = []string{
+	"empty_thought", "invalid_thought_number", "invalid_total_thoughts",
+	"exceeds_total_thoughts", "missing_revises_thought",
+}
+
+// fuzzEpoch is the fixed clock of FuzzCallSequence, which keeps every call in one session
+var fuzzEpoch = time.Unix(1700000000, 0).UTC()
+
+// checkHistory verifies the invariants every stored session must hold: its
+// thoughts are those in want, in order, and Branches lists exactly the
+// thought numbers of each branch's thoughts
+func checkHistory(t *testing.T, history *ThoughtHistory, want []ThoughtRequest) {
+	t.Helper()
+
+	if !reflect.DeepEqual(history.Thoughts, want) && !(len(history.Thoughts) == 0 && len(want) == 0) {
+		t.Fatalf("Thoughts = %+v, want %+v", history.Thoughts, want)
+	}
+	branches := make(map[string][]int)
+	for _, thought := range want {
+		if thought.BranchID != "" {
+			branches[thought.BranchID] = append(branches[thought.BranchID], thought.ThoughtNumber)
+		}
+	}
+	if !reflect.DeepEqual(history.Branches, branches) {
+		t.Fatalf("Branches = %v, want %v", history.Branches, branches)
+	}
+	if history.CreatedAt.IsZero() {
+		t.Fatal("CreatedAt is not set")
+	}
+}
+
+func FuzzDecodeThoughtArguments(f *testing.F) {
+	f.Add([]byte(`{"thought":"Start","nextThoughtNeeded":true,"thoughtNumber":1,"totalThoughts":3}`))
+	f.Add([]byte(`{"thought":"Alt","nextThoughtNeeded":false,"thoughtNumber":2,"totalThoughts":2,"branchFromThought":1,"branchId":"b","isRevision":true,"revisesThought":1,"needsMoreThoughts":true}`))
+	f.Add([]byte(`{"thought":1,"nextThoughtNeeded":"yes","thoughtNumber":"1","totalThoughts":2.5}`))
+	f.Add([]byte(`{"thoughtNumber":1e300,"totalThoughts":-9007199254740993}`))
+	f.Add([]byte(`{"Thought":"case","THOUGHTNUMBER":1}`))
+	f.Add([]byte(`["thought"]`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`"text"`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var args interface{}
+		if err := json.Unmarshal(data, &args); err != nil {
+			// Not JSON: the fallback path must fail without panicking
+			if _, err := decodeThoughtArguments(json.RawMessage(data)); err == nil {
+				t.Fatalf("Expected invalid JSON %q to fail", data)
+			}
+			return
+		}
+
+		fromMap, mapErr := decodeThoughtArguments(args)
+		fromJSON, jsonErr := decodeThoughtArguments(json.RawMessage(data))
+		if (mapErr == nil) != (jsonErr == nil) {
+			t.Fatalf("Decode paths disagree on %q: %v vs %v", data, mapErr, jsonErr)
+		}
+		if mapErr != nil {
+			if _, isObject := args.(map[string]interface{}); isObject {
+				t.Fatalf("Decoding object %q failed: %v", data, mapErr)
+			}
+			return
+		}
+		if fromMap != fromJSON {
+			t.Fatalf("Decode paths disagree on %q: %+v vs %+v", data, fromMap, fromJSON)
+		}
+
+		// A decoded request decodes to itself
+		encoded, err := json.Marshal(fromMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var roundTrip interface{}
+		if err := json.Unmarshal(encoded, &roundTrip); err != nil {
+			t.Fatal(err)
+		}
+		if again, err := decodeThoughtArguments(roundTrip); err != nil || again != fromMap {
+			t.Fatalf("Round trip of %+v gave %+v (%v)", fromMap, again, err)
+		}
+	})
+}
+
+func FuzzValidateThoughtRequest(f *testing.F) {
+	f.Add("Start", true, 1, 3, false, 0, 0, "", false)
+	f.Add("", false, 0, 0, true, 0, 0, "b", true)
+	f.Add("Over", false, 5, 3, false, 0, 0, "", true)
+	f.Add("Revise", true, 2, 3, true, -1, 1, "b", false)
+
+	s := NewSequentialThinkingServer()
+	f.Fuzz(func(t *testing.T, thought string, next bool, number, total int, isRevision bool, revises, branchFrom int, branchID string, needsMore bool) {
+		req := ThoughtRequest{
+			Thought:           thought,
+			NextThoughtNeeded: next,
+			ThoughtNumber:     number,
+			TotalThoughts:     total,
+			IsRevision:        isRevision,
+			RevisesThought:    revises,
+			BranchFromThought: branchFrom,
+			BranchID:          branchID,
+			NeedsMoreThoughts: needsMore,
+		}
+		before := req
+
+		err := s.validateThoughtRequest(&req)
+		if req != before {
+			t.Fatalf("validateThoughtRequest modified the request: %+v", req)
+		}
+		if err != nil {
+			var verr *validationError
+			if !errors.As(err, &verr) || !slices.Contains(validationReasons, verr.Reason) || verr.Message == "" {
+				t.Fatalf("Unexpected error %#v", err)
+			}
+			return
+		}
+
+		if thought == "" || number < 1 || total < 1 || (number > total && !needsMore) || (isRevision && revises < 1) {
+			t.Fatalf("Accepted invalid request %+v", req)
+		}
+	})
+}
+
+func FuzzSessionStore(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	f.Add([]byte{0, 0, 0, 1, 1, 1, 2, 2, 2, 3, 3, 3})
+	f.Add([]byte{5, 5, 5, 5, 0, 1, 9, 9, 9})
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		store := newMemoryStore(time.Now)
+		model := make(map[string][]ThoughtRequest)
+
+		// Each op is two bytes: the operation and session, then the thought
+		for i := 0; i+1 < len(ops); i += 2 {
+			sessionID := string(rune('a' + ops[i]%3))
+			switch ops[i] / 3 % 4 {
+			case 0, 1:
+				req := ThoughtRequest{Thought: "t", ThoughtNumber: int(ops[i+1]%8) + 1, TotalThoughts: 8}
+				if ops[i+1]&0x80 != 0 {
+					req.BranchID = string(rune('x' + ops[i+1]%2))
+					req.BranchFromThought = 1
+				}
+				if err := store.Append(sessionID, req); err != nil {
+					t.Fatalf("Append failed: %v", err)
+				}
+				model[sessionID] = append(model[sessionID], req)
+			case 2:
+				err := store.Delete(sessionID)
+				if _, exists := model[sessionID]; exists != (err == nil) {
+					t.Fatalf("Delete(%s) = %v with session present: %v", sessionID, err, exists)
+				}
+				delete(model, sessionID)
+			case 3:
+				// Mutating a returned copy must not leak into the store
+				if history, ok := store.Get(sessionID); ok {
+					history.Thoughts = append(history.Thoughts[:0], ThoughtRequest{Thought: "mutated"})
+					for branch := range history.Branches {
+						history.Branches[branch] = nil
+					}
+				}
+			}
+		}
+
+		ids := make([]string, 0, len(model))
+		for id := range model {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		if got := store.List(); !slices.Equal(got, ids) {
+			t.Fatalf("List() = %v, want %v", got, ids)
+		}
+		for _, id := range ids {
+			history, ok := store.Get(id)
+			if !ok {
+				t.Fatalf("Session %s is missing", id)
+			}
+			checkHistory(t, history, model[id])
+		}
+	})
+}
+
+func FuzzCallSequence(f *testing.F) {
+	f.Add([]byte(`{"thought":"a","nextThoughtNeeded":true,"thoughtNumber":1,"totalThoughts":2}
+{"thought":"","nextThoughtNeeded":true,"thoughtNumber":2,"totalThoughts":2}
+{"thought":"b","nextThoughtNeeded":false,"thoughtNumber":2,"totalThoughts":2,"branchId":"x","branchFromThought":1}`))
+	f.Add([]byte(`{"thought":"r","nextThoughtNeeded":true,"thoughtNumber":3,"totalThoughts":2,"needsMoreThoughts":true,"isRevision":true,"revisesThought":1}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		thinker := NewSequentialThinkingServer(
+			WithLogger(slog.New(slog.DiscardHandler)),
+			WithClock(func() time.Time { return fuzzEpoch }),
+		)
+
+		// Every accepted call must land in the single session, and only those
+		var accepted []ThoughtRequest
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			var args interface{}
+			if json.Unmarshal(line, &args) != nil {
+				args = string(line)
+			}
+			result, err := thinker.CallTool(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "sequentialthinking", Arguments: args},
+			})
+			if err != nil {
+				continue
+			}
+			if result == nil || result.IsError {
+				t.Fatalf("Successful call returned %+v", result)
+			}
+			req, _ := decodeThoughtArguments(args)
+			if err := thinker.validateThoughtRequest(&req); err != nil {
+				t.Fatalf("Stored a thought that fails validation: %+v", req)
+			}
+			accepted = append(accepted, req)
+		}
+
+		store := thinker.tenants.create(DefaultTenant).store
+		if len(accepted) == 0 {
+			if ids := store.List(); len(ids) != 0 {
+				t.Fatalf("Sessions %v created without accepted calls", ids)
+			}
+			return
+		}
+		history, ok := store.Get(fmt.Sprintf("session_%d", fuzzEpoch.Unix()))
+		if !ok {
+			t.Fatalf("Session missing, have %v", store.List())
+		}
+		checkHistory(t, history, accepted)
+	})
+}
@@ -0,0 +1,44 @@
+package thinking
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// thoughtLogValue returns the thought text as it should appear in logs
+func (s *SequentialThinkingServer) thoughtLogValue(thought string) string {
+	if s.redactThoughts {
+		return fmt.Sprintf("[redacted %d bytes]", len(thought))
+	}
+
+	return thought
+}
+
+// logCall writes one record per tool call with its request fields, latency and error
+func (s *SequentialThinkingServer) logCall(sessionID string, req *ThoughtRequest, outcome string, latency time.Duration, err error) {
+	attrs := []any{
+		slog.String("outcome", outcome),
+		slog.Duration("latency", latency),
+	}
+	if sessionID != "" {
+		attrs = append(attrs,
+			slog.String("session", sessionID),
+			slog.Int("thought_number", req.ThoughtNumber),
+			slog.Int("total_thoughts", req.TotalThoughts),
+			slog.String("thought", s.thoughtLogValue(req.Thought)),
+		)
+		if req.BranchID != "" {
+			attrs = append(attrs, slog.String("branch", req.BranchID))
+		}
+		if req.IsRevision {
+			attrs = append(attrs, slog.Int("revises_thought", req.RevisesThought))
+		}
+	}
+
+	if err != nil {
+		s.logger.Warn("tool call failed", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	s.logger.Info("tool call", attrs...)
+}
@@ -0,0 +1,63 @@
+package thinking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCallToolLogging(t *testing.T) {
+	tests := []struct {
+		name   string
+		redact bool
+	}{
+		{name: "plain", redact: false},
+		{name: "redacted", redact: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			server := NewSequentialThinkingServer(
+				WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+				WithThoughtRedaction(tt.redact),
+			)
+
+			_, err := server.CallTool(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name: "sequentialthinking",
+					Arguments: map[string]interface{}{
+						"thought":           "secret plan",
+						"nextThoughtNeeded": true,
+						"thoughtNumber":     float64(1),
+						"totalThoughts":     float64(2),
+						"branchId":          "alt",
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("CallTool failed: %v", err)
+			}
+
+			var record map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("Log output is not a single JSON record: %v: %s", err, buf.String())
+			}
+
+			for _, field := range []string{"session", "thought_number", "branch", "latency", "outcome"} {
+				if _, ok := record[field]; !ok {
+					t.Errorf("Log record is missing field '%s': %s", field, buf.String())
+				}
+			}
+
+			if leaked := strings.Contains(buf.String(), "secret plan"); leaked == tt.redact {
+				t.Errorf("Thought text present = %v with redact = %v: %s", leaked, tt.redact, buf.String())
+			}
+		})
+	}
+}
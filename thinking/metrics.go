@@ -0,0 +1,123 @@
+package thinking
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Outcomes recorded for every CallTool invocation, in metrics, logs and the
+// call log
+const (
+	OutcomeSuccess         = "success"
+	OutcomeUnknownTool     = "unknown_tool"
+	OutcomeInvalidArgs     = "invalid_arguments"
+	OutcomeValidationError = "validation_error"
+	OutcomeRateLimited     = "rate_limited"
+	OutcomeRejected        = "rejected"
+	OutcomeUnavailable     = "unavailable"
+	OutcomeError           = "error"
+)
+
+// metrics holds the Prometheus collectors of one server instance. Each server
+// owns its registry so several instances (and tests) never collide.
+type metrics struct {
+	registry *prometheus.Registry
+
+	toolCalls          *prometheus.CounterVec
+	validationFailures *prometheus.CounterVec
+	revisions          prometheus.Counter
+	branchesCreated    prometheus.Counter
+	thoughtsPerSession prometheus.Histogram
+	callDuration       prometheus.Histogram
+	webhookDeliveries  *prometheus.CounterVec
+	eventsDropped      prometheus.Counter
+}
+
+// newMetrics creates and registers the collectors; activeSessions is sampled
+// on every scrape to report the number of sessions held in the store
+func newMetrics(activeSessions func() float64) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sequentialthinking_tool_calls_total",
+			Help: "Tool calls by outcome.",
+		}, []string{"outcome"}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sequentialthinking_validation_failures_total",
+			Help: "Rejected thoughts by validation failure reason.",
+		}, []string{"reason"}),
+		revisions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sequentialthinking_revisions_total",
+			Help: "Thoughts that revise an earlier thought.",
+		}),
+		branchesCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sequentialthinking_branches_created_total",
+			Help: "Branches started across all sessions.",
+		}),
+		thoughtsPerSession: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "sequentialthinking_thoughts_per_session",
+			Help:    "Number of thoughts in a session when it completes.",
+			Buckets: []float64{1, 2, 3, 5, 8, 13, 21, 34, 55},
+		}),
+		callDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "sequentialthinking_tool_call_duration_seconds",
+			Help:    "Latency of tool calls.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}),
+		webhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sequentialthinking_webhook_deliveries_total",
+			Help: "Webhook deliveries, summed over all receiving URLs, by outcome: delivered, failed or dropped.",
+		}, []string{"outcome"}),
+		eventsDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sequentialthinking_events_dropped_total",
+			Help: "Session events a subscriber missed because its buffer was full.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.toolCalls,
+		m.validationFailures,
+		m.revisions,
+		m.branchesCreated,
+		m.thoughtsPerSession,
+		m.callDuration,
+		m.webhookDeliveries,
+		m.eventsDropped,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "sequentialthinking_active_sessions",
+			Help: "Sessions currently held in the session store.",
+		}, activeSessions),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// observeCall records the outcome and latency of one tool call
+func (m *metrics) observeCall(outcome string, elapsed time.Duration) {
+	m.toolCalls.WithLabelValues(outcome).Inc()
+	m.callDuration.Observe(elapsed.Seconds())
+}
+
+// observeThought records what a stored thought did to its session
+func (m *metrics) observeThought(req *ThoughtRequest, history *ThoughtHistory) {
+	if req.IsRevision {
+		m.revisions.Inc()
+	}
+	if req.BranchID != "" && len(history.Branches[req.BranchID]) == 1 {
+		m.branchesCreated.Inc()
+	}
+	if !req.NextThoughtNeeded {
+		m.thoughtsPerSession.Observe(float64(len(history.Thoughts)))
+	}
+}
+
+// MetricsHandler serves the server's metrics in the Prometheus exposition format
+func (s *SequentialThinkingServer) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(s.metrics.registry, promhttp.HandlerOpts{})
+}
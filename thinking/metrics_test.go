@@ -0,0 +1,60 @@
+package thinking
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCallToolMetrics(t *testing.T) {
+	server := NewSequentialThinkingServer()
+
+	calls := []map[string]interface{}{
+		{"thought": "Start", "nextThoughtNeeded": true, "thoughtNumber": float64(1), "totalThoughts": float64(3)},
+		{"thought": "Rethink", "nextThoughtNeeded": true, "thoughtNumber": float64(2), "totalThoughts": float64(3), "isRevision": true, "revisesThought": float64(1)},
+		{"thought": "Alternative", "nextThoughtNeeded": false, "thoughtNumber": float64(3), "totalThoughts": float64(3), "branchId": "alt", "branchFromThought": float64(1)},
+		{"thought": "", "nextThoughtNeeded": false, "thoughtNumber": float64(1), "totalThoughts": float64(1)},
+	}
+
+	for _, args := range calls {
+		_, _ = server.CallTool(context.Background(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "sequentialthinking", Arguments: args},
+		})
+	}
+
+	m := server.metrics
+	if got := testutil.ToFloat64(m.toolCalls.WithLabelValues(OutcomeSuccess)); got != 3 {
+		t.Errorf("Expected 3 successful calls, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.toolCalls.WithLabelValues(OutcomeValidationError)); got != 1 {
+		t.Errorf("Expected 1 failed call, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.validationFailures.WithLabelValues("empty_thought")); got != 1 {
+		t.Errorf("Expected 1 empty_thought failure, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.revisions); got != 1 {
+		t.Errorf("Expected 1 revision, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.branchesCreated); got != 1 {
+		t.Errorf("Expected 1 branch, got %v", got)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	server := NewSequentialThinkingServer()
+
+	rec := httptest.NewRecorder()
+	server.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := io.ReadAll(rec.Body)
+	for _, name := range []string{"sequentialthinking_active_sessions", "sequentialthinking_tool_call_duration_seconds"} {
+		if !strings.Contains(string(body), name) {
+			t.Errorf("Metrics output does not contain '%s'", name)
+		}
+	}
+}
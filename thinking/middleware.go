@@ -0,0 +1,72 @@
+package thinking
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ToolCall is a tool call that passed rate limiting and argument decoding,
+// on its way to be validated and stored
+type ToolCall struct {
+	// Request is the decoded thought. Middleware may rewrite it before
+	// calling the next handler, for example to redact it; the logs and the
+	// call log record the thought as it was finally handled.
+	Request ThoughtRequest
+	// SessionID names the session the thought is appended to. Middleware
+	// may change it, but calls moved to a session outside the caller's
+	// subject fail.
+	SessionID string
+	// Time is the server clock at the start of the call
+	Time time.Time
+	// Raw is the tool call as the client sent it
+	Raw mcp.CallToolRequest
+
+	// tenant and store are fixed when the call is admitted; middleware
+	// cannot move a call to another tenant
+	tenant string
+	store  SessionStore
+	// outcome is set by handleThought; empty means middleware answered
+	outcome string
+}
+
+// Tenant returns the tenant the call belongs to
+func (c *ToolCall) Tenant() string {
+	return c.tenant
+}
+
+// History returns a copy of the session the call belongs to. Until the call
+// is passed on it lacks the call's thought; false means the call starts a
+// new session.
+func (c *ToolCall) History() (*ThoughtHistory, bool) {
+	return c.store.Get(c.SessionID)
+}
+
+// Handler handles a tool call. Like CallTool it reports invalid calls with
+// an error and refusals the client should see with an error result.
+type Handler func(ctx context.Context, call *ToolCall) (*mcp.CallToolResult, error)
+
+// Middleware wraps a Handler to add behaviour around tool calls. It can
+// inspect or rewrite the call and pass it on to next, or short-circuit by
+// returning without calling next, typically with mcp.NewToolResultError.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware adds middleware around tool calls. Middleware runs in the
+// order it is added, across repeated options: the first one sees the call
+// first and the result last.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(s *SequentialThinkingServer) {
+		s.middleware = append(s.middleware, middleware...)
+	}
+}
+
+// chain returns the chain of middleware ending in handleThought
+func (s *SequentialThinkingServer) chain() Handler {
+	h := s.handleThought
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		h = s.middleware[i](h)
+	}
+
+	return h
+}
@@ -0,0 +1,165 @@
+package thinking
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var trace []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *ToolCall) (*mcp.CallToolResult, error) {
+				trace = append(trace, name+" before")
+				result, err := next(ctx, call)
+				trace = append(trace, name+" after")
+				return result, err
+			}
+		}
+	}
+	server := NewSequentialThinkingServer(
+		WithMiddleware(record("auth"), record("audit")),
+		WithMiddleware(record("cache")),
+	)
+
+	if _, err := server.CallTool(context.Background(), thoughtCall(1, true)); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+
+	want := "auth before, audit before, cache before, cache after, audit after, auth after"
+	if got := strings.Join(trace, ", "); got != want {
+		t.Errorf("Middleware ran as %s, want %s", got, want)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	deny := func(next Handler) Handler {
+		return func(ctx context.Context, call *ToolCall) (*mcp.CallToolResult, error) {
+			if call.Request.ThoughtNumber > 1 {
+				return mcp.NewToolResultError("only one thought allowed"), nil
+			}
+			return next(ctx, call)
+		}
+	}
+	server := NewSequentialThinkingServer(WithMiddleware(deny))
+
+	if result, _ := server.CallTool(context.Background(), thoughtCall(1, true)); result.IsError {
+		t.Fatal("First thought was refused")
+	}
+	result, err := server.CallTool(context.Background(), thoughtCall(2, true))
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if !result.IsError || !contains(result.Content[0].(mcp.TextContent).Text, "only one thought") {
+		t.Errorf("Expected the middleware's error result, got %+v", result)
+	}
+
+	store := server.tenants.create(DefaultTenant).store
+	history, _ := store.Get(store.List()[0])
+	if len(history.Thoughts) != 1 {
+		t.Errorf("Expected the refused thought not to be stored, got %d thoughts", len(history.Thoughts))
+	}
+	if got := testutil.ToFloat64(server.metrics.toolCalls.WithLabelValues(OutcomeRejected)); got != 1 {
+		t.Errorf("Expected 1 rejected call, got %v", got)
+	}
+}
+
+func TestMiddlewareSeesSession(t *testing.T) {
+	var seen []int
+	redact := func(next Handler) Handler {
+		return func(ctx context.Context, call *ToolCall) (*mcp.CallToolResult, error) {
+			if call.Tenant() != "acme" || !strings.HasPrefix(call.SessionID, "alice/") {
+				t.Errorf("Unexpected tenant %q and session %q", call.Tenant(), call.SessionID)
+			}
+			history, ok := call.History()
+			if ok {
+				seen = append(seen, len(history.Thoughts))
+			} else {
+				seen = append(seen, 0)
+			}
+			call.Request.Thought = "[redacted]"
+			return next(ctx, call)
+		}
+	}
+	server := NewSequentialThinkingServer(WithMiddleware(redact))
+	ctx := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice", Tenant: "acme"})
+
+	for i := 1; i <= 2; i++ {
+		if _, err := server.CallTool(ctx, thoughtCall(i, true)); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+	}
+
+	if len(seen) != 2 || seen[0] != 0 || seen[1] != 1 {
+		t.Errorf("Expected the middleware to see 0 then 1 earlier thoughts, got %v", seen)
+	}
+	store := server.tenants.create("acme").store
+	history, _ := store.Get(store.List()[0])
+	for _, thought := range history.Thoughts {
+		if thought.Thought != "[redacted]" {
+			t.Errorf("Expected the rewritten thought to be stored, got %q", thought.Thought)
+		}
+	}
+}
+
+// recordingCallLog keeps call records in memory
+type recordingCallLog struct {
+	records []CallRecord
+}
+
+func (l *recordingCallLog) Append(rec CallRecord) error {
+	l.records = append(l.records, rec)
+	return nil
+}
+
+func (l *recordingCallLog) Close() error { return nil }
+
+func TestMiddlewareRewriteReachesCallLog(t *testing.T) {
+	redact := func(next Handler) Handler {
+		return func(ctx context.Context, call *ToolCall) (*mcp.CallToolResult, error) {
+			call.Request.Thought = "[redacted]"
+			return next(ctx, call)
+		}
+	}
+	callLog := &recordingCallLog{}
+	server := NewSequentialThinkingServer(WithMiddleware(redact), WithCallLog(callLog))
+
+	request := thoughtCall(1, true)
+	request.Params.Arguments.(map[string]interface{})["thought"] = "secret plan"
+	if _, err := server.CallTool(context.Background(), request); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+
+	if len(callLog.records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(callLog.records))
+	}
+	rec := callLog.records[0]
+	if strings.Contains(string(rec.Arguments), "secret") || !strings.Contains(string(rec.Arguments), `"thought":"[redacted]"`) {
+		t.Errorf("Expected the redacted arguments in the call log, got %s", rec.Arguments)
+	}
+	if rec.Thought == nil || rec.Thought.Thought != "[redacted]" {
+		t.Errorf("Expected the redacted thought in the call log, got %+v", rec.Thought)
+	}
+}
+
+func TestMiddlewareCannotMoveCallToAnotherSubject(t *testing.T) {
+	hijack := func(next Handler) Handler {
+		return func(ctx context.Context, call *ToolCall) (*mcp.CallToolResult, error) {
+			call.SessionID = "alice/session_1"
+			return next(ctx, call)
+		}
+	}
+	server := NewSequentialThinkingServer(WithMiddleware(hijack))
+	bob := ContextWithPrincipal(context.Background(), &Principal{Subject: "bob"})
+
+	if _, err := server.CallTool(bob, thoughtCall(1, true)); err == nil {
+		t.Fatal("Expected a call moved to another subject's session to fail")
+	}
+	if ids := server.tenants.create(DefaultTenant).store.List(); len(ids) != 0 {
+		t.Errorf("Expected nothing stored, got %v", ids)
+	}
+}
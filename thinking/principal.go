@@ -0,0 +1,59 @@
+package thinking
+
+import (
+	"context"
+	"strings"
+)
+
+// Principal identifies an authenticated caller
+type Principal struct {
+	// Subject is the stable identity sessions are scoped to
+	Subject string
+	// Method names the authenticator that admitted the caller
+	Method string
+	// Tenant is the tenant asserted by the credentials, if any
+	Tenant string
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the principal
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller authenticated for this request, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// subjectEscaper keeps "/" out of the subject in session IDs, so that no
+// subject's prefix is the start of another's: "alice/x" becomes "alice%2Fx"
+var subjectEscaper = strings.NewReplacer("%", "%25", "/", "%2F")
+
+// sessionPrefix is the start of every session ID scoped to p
+func sessionPrefix(p *Principal) string {
+	return subjectEscaper.Replace(p.Subject) + "/"
+}
+
+// scopedSessionID prefixes a session ID with the caller's subject so
+// sessions of different principals never share a ThoughtHistory
+func scopedSessionID(ctx context.Context, sessionID string) string {
+	if p, ok := PrincipalFromContext(ctx); ok {
+		return sessionPrefix(p) + sessionID
+	}
+
+	return sessionID
+}
+
+// OwnsSession reports whether the caller in ctx may read or change the
+// session: authenticated callers only own sessions scoped to their subject.
+// Without a principal every session of the tenant is reachable.
+func OwnsSession(ctx context.Context, sessionID string) bool {
+	if p, ok := PrincipalFromContext(ctx); ok {
+		return strings.HasPrefix(sessionID, sessionPrefix(p))
+	}
+
+	return true
+}
@@ -0,0 +1,107 @@
+package thinking
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSessionsScopedToPrincipal(t *testing.T) {
+	server := NewSequentialThinkingServer()
+	alice := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice"})
+	bob := ContextWithPrincipal(context.Background(), &Principal{Subject: "bob"})
+
+	call := func(ctx context.Context, number int, next bool) string {
+		result, err := server.CallTool(ctx, mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Name: "sequentialthinking",
+				Arguments: map[string]interface{}{
+					"thought":           "Thinking",
+					"nextThoughtNeeded": next,
+					"thoughtNumber":     float64(number),
+					"totalThoughts":     float64(3),
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	call(alice, 1, true)
+	call(alice, 2, true)
+
+	// Bob's first thought must not see Alice's two thoughts in its summary
+	if text := call(bob, 1, false); strings.Contains(text, "Summary") {
+		t.Errorf("Bob's session includes another principal's thoughts: %s", text)
+	}
+
+	for _, id := range server.tenants.create(DefaultTenant).store.List() {
+		if !strings.HasPrefix(id, "alice/") && !strings.HasPrefix(id, "bob/") {
+			t.Errorf("Session '%s' is not scoped to a principal", id)
+		}
+	}
+}
+
+func TestSessionResourceScopedToPrincipal(t *testing.T) {
+	server := NewSequentialThinkingServer()
+	alice := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice"})
+	bob := ContextWithPrincipal(context.Background(), &Principal{Subject: "bob"})
+	if _, err := server.CallTool(alice, mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "sequentialthinking",
+			Arguments: map[string]interface{}{
+				"thought":           "Thinking",
+				"nextThoughtNeeded": true,
+				"thoughtNumber":     float64(1),
+				"totalThoughts":     float64(3),
+			},
+		},
+	}); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	ids := server.tenants.create(DefaultTenant).store.List()
+	if len(ids) != 1 {
+		t.Fatalf("Expected one session, got %v", ids)
+	}
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = sessionURIPrefix + url.PathEscape(ids[0])
+	if _, err := server.SessionResource(alice, request); err != nil {
+		t.Fatalf("Expected alice to read the session, got %v", err)
+	}
+	if _, err := server.SessionResource(bob, request); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for another subject's session, got %v", err)
+	}
+}
+
+func TestSubjectsWithSlashOwnTheirSessionsOnly(t *testing.T) {
+	server := NewSequentialThinkingServer()
+	alice := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice"})
+	aliceX := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice/x"})
+	if _, err := server.CallTool(aliceX, thoughtCall(1, true)); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	ids := server.tenants.create(DefaultTenant).store.List()
+	if len(ids) != 1 || !strings.HasPrefix(ids[0], "alice%2Fx/") {
+		t.Fatalf("Expected one session scoped to the escaped subject, got %v", ids)
+	}
+
+	if OwnsSession(alice, ids[0]) {
+		t.Errorf("Subject alice owns a session of subject alice/x")
+	}
+	if !OwnsSession(aliceX, ids[0]) {
+		t.Errorf("Subject alice/x does not own its own session %s", ids[0])
+	}
+	if err := server.DeleteSession(alice, ids[0]); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound deleting another subject's session, got %v", err)
+	}
+	if err := server.DeleteSession(aliceX, ids[0]); err != nil {
+		t.Errorf("DeleteSession failed for the owner: %v", err)
+	}
+}
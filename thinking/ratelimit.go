@@ -0,0 +1,175 @@
+package thinking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// errRateLimited is returned when a client calls faster than its token bucket allows
+	errRateLimited = errors.New("rate limit exceeded")
+	// errQuotaExceeded is returned when a client has used up its daily thought quota
+	errQuotaExceeded = errors.New("daily thought quota exceeded")
+)
+
+// RateLimitConfig configures per-client limits; zero values disable a limit
+type RateLimitConfig struct {
+	// Rate is the sustained number of tool calls per second per client,
+	// whether or not they store a thought
+	Rate float64 `json:"rate"`
+	// Burst is the number of calls a client may make at once
+	Burst int `json:"burst"`
+	// DailyQuota is the number of stored thoughts per client per UTC day;
+	// calls that are malformed or fail validation do not count
+	DailyQuota int `json:"dailyQuota"`
+}
+
+// clientLimit is the limiter state of one client
+type clientLimit struct {
+	bucket   *rate.Limiter
+	day      string
+	used     int
+	lastSeen time.Time
+}
+
+// rateLimiter enforces token-bucket rate limits and daily quotas per client key
+type rateLimiter struct {
+	cfg RateLimitConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimit
+	lastSweep time.Time
+}
+
+// newRateLimiter returns a limiter running on now, or nil when cfg disables
+// every limit
+func newRateLimiter(cfg RateLimitConfig, now func() time.Time) *rateLimiter {
+	if cfg.Rate <= 0 && cfg.DailyQuota <= 0 {
+		return nil
+	}
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+
+	return &rateLimiter{
+		cfg:     cfg,
+		now:     now,
+		clients: make(map[string]*clientLimit),
+	}
+}
+
+// allow admits one call for key, reserving one thought of its daily quota.
+// When the call is refused it returns how long the client should wait
+// before retrying.
+func (l *rateLimiter) allow(key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	c := l.clients[key]
+	if c == nil {
+		c = &clientLimit{}
+		if l.cfg.Rate > 0 {
+			c.bucket = rate.NewLimiter(rate.Limit(l.cfg.Rate), l.cfg.Burst)
+		}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+
+	today := now.UTC().Format(time.DateOnly)
+	if c.day != today {
+		c.day = today
+		c.used = 0
+	}
+	if l.cfg.DailyQuota > 0 && c.used >= l.cfg.DailyQuota {
+		midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		return midnight.Sub(now), errQuotaExceeded
+	}
+
+	if c.bucket != nil {
+		reservation := c.bucket.ReserveN(now, 1)
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			return delay, errRateLimited
+		}
+	}
+
+	c.used++
+
+	return 0, nil
+}
+
+// refund returns the quota reserved by allow for a call that stored no
+// thought. Reservations from a previous day are not refunded, since that
+// day's count has already been reset.
+func (l *rateLimiter) refund(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.clients[key]
+	if c != nil && c.used > 0 && c.day == l.now().UTC().Format(time.DateOnly) {
+		c.used--
+	}
+}
+
+// sweep forgets clients idle for more than a day, at most once an hour
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Hour {
+		return
+	}
+	l.lastSweep = now
+
+	for key, c := range l.clients {
+		if now.Sub(c.lastSeen) > 24*time.Hour {
+			delete(l.clients, key)
+		}
+	}
+}
+
+type remoteAddrKey struct{}
+
+// ContextWithRemoteAddr returns a copy of ctx carrying the caller's network
+// address, which rate limits unauthenticated callers by
+func ContextWithRemoteAddr(ctx context.Context, host string) context.Context {
+	return context.WithValue(ctx, remoteAddrKey{}, host)
+}
+
+// clientKey identifies the caller for rate limiting. An authenticated
+// principal is preferred, then the remote IP, and finally the MCP session:
+// session IDs are chosen by unauthenticated HTTP clients, so they are only
+// trusted where there is no network peer, as in stdio mode or on a Unix
+// socket, whose file mode already limits who can connect.
+func clientKey(ctx context.Context) string {
+	if p, ok := PrincipalFromContext(ctx); ok {
+		return "principal:" + p.Subject
+	}
+	if host, ok := ctx.Value(remoteAddrKey{}).(string); ok && host != "" {
+		return "ip:" + host
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return "session:" + session.SessionID()
+	}
+
+	return "anonymous"
+}
+
+// rateLimitedResult is the tool error returned to throttled clients; the
+// retry delay is given both in the text and as _meta.retryAfterSeconds
+func rateLimitedResult(err error, retryAfter time.Duration) *mcp.CallToolResult {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	result := mcp.NewToolResultError(fmt.Sprintf("%v, retry after %ds", err, seconds))
+	result.Meta = map[string]any{"retryAfterSeconds": seconds}
+
+	return result
+}
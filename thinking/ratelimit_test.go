@@ -0,0 +1,150 @@
+package thinking
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(RateLimitConfig{Rate: 1, Burst: 2}, func() time.Time { return now })
+
+	for i := 0; i < 2; i++ {
+		if _, err := limiter.allow("alice"); err != nil {
+			t.Fatalf("Call %d within burst was refused: %v", i+1, err)
+		}
+	}
+
+	retryAfter, err := limiter.allow("alice")
+	if !errors.Is(err, errRateLimited) {
+		t.Fatalf("Expected errRateLimited, got %v", err)
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("Expected retry-after within 1s, got %v", retryAfter)
+	}
+
+	if _, err := limiter.allow("bob"); err != nil {
+		t.Errorf("Other clients must not share a bucket: %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := limiter.allow("alice"); err != nil {
+		t.Errorf("Expected a refilled token after 1s, got %v", err)
+	}
+}
+
+func TestRateLimiterDailyQuota(t *testing.T) {
+	now := time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(RateLimitConfig{DailyQuota: 2}, func() time.Time { return now })
+
+	for i := 0; i < 2; i++ {
+		if _, err := limiter.allow("alice"); err != nil {
+			t.Fatalf("Call %d within quota was refused: %v", i+1, err)
+		}
+	}
+
+	retryAfter, err := limiter.allow("alice")
+	if !errors.Is(err, errQuotaExceeded) {
+		t.Fatalf("Expected errQuotaExceeded, got %v", err)
+	}
+	if retryAfter != time.Hour {
+		t.Errorf("Expected retry-after until midnight UTC (1h), got %v", retryAfter)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := limiter.allow("alice"); err != nil {
+		t.Errorf("Expected quota to reset on a new day, got %v", err)
+	}
+}
+
+func TestNewRateLimiterDisabled(t *testing.T) {
+	if limiter := newRateLimiter(RateLimitConfig{}, time.Now); limiter != nil {
+		t.Error("Expected nil limiter when all limits are disabled")
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	ipCtx := ContextWithRemoteAddr(context.Background(), "192.0.2.7")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "principal wins", ctx: ContextWithPrincipal(ipCtx, &Principal{Subject: "alice"}), want: "principal:alice"},
+		{name: "remote IP", ctx: ipCtx, want: "ip:192.0.2.7"},
+		{name: "nothing known", ctx: context.Background(), want: "anonymous"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientKey(tt.ctx); got != tt.want {
+				t.Errorf("clientKey() = '%s', want '%s'", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallToolRateLimited(t *testing.T) {
+	server := NewSequentialThinkingServer(WithRateLimit(RateLimitConfig{DailyQuota: 1}))
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "sequentialthinking",
+			Arguments: map[string]interface{}{
+				"thought":           "Thinking",
+				"nextThoughtNeeded": true,
+				"thoughtNumber":     float64(1),
+				"totalThoughts":     float64(2),
+			},
+		},
+	}
+
+	if _, err := server.CallTool(context.Background(), request); err != nil {
+		t.Fatalf("First call failed: %v", err)
+	}
+
+	result, err := server.CallTool(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Rate limiting must produce a tool error, not a protocol error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("Expected IsError on throttled call")
+	}
+	if _, ok := result.Meta["retryAfterSeconds"]; !ok {
+		t.Error("Expected retryAfterSeconds in result metadata")
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !contains(text, "retry after") {
+		t.Errorf("Expected retry hint in text, got '%s'", text)
+	}
+}
+
+func TestDailyQuotaCountsStoredThoughts(t *testing.T) {
+	server := NewSequentialThinkingServer(WithRateLimit(RateLimitConfig{DailyQuota: 1}))
+	invalid := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "sequentialthinking",
+			Arguments: map[string]interface{}{
+				"thought":           "",
+				"nextThoughtNeeded": true,
+				"thoughtNumber":     float64(1),
+				"totalThoughts":     float64(2),
+			},
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := server.CallTool(context.Background(), invalid); err == nil {
+			t.Fatal("Expected the empty thought to fail validation")
+		}
+	}
+	if result, _ := server.CallTool(context.Background(), thoughtCall(1, true)); result.IsError {
+		t.Fatal("Invalid calls used up the daily thought quota")
+	}
+	if result, _ := server.CallTool(context.Background(), thoughtCall(2, true)); !result.IsError {
+		t.Error("Expected the stored thought to use up the quota")
+	}
+}
@@ -0,0 +1,153 @@
+package thinking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Session resource URIs. IDs are path-escaped, so "alice/session_1" is
+// sequentialthinking://sessions/alice%2Fsession_1.
+const (
+	sessionURITemplate       = "sequentialthinking://sessions/{id}"
+	sessionExportURITemplate = "sequentialthinking://sessions/{id}/export{?format}"
+	sessionURIPrefix         = "sequentialthinking://sessions/"
+)
+
+// ExportFormat describes one format sessions can be exported in
+type ExportFormat struct {
+	ContentType string
+	Extension   string
+}
+
+// ExportFormats are the formats sessions can be exported in, by name
+var ExportFormats = map[string]ExportFormat{
+	"json":     {ContentType: "application/json", Extension: "json"},
+	"markdown": {ContentType: "text/markdown; charset=utf-8", Extension: "md"},
+}
+
+// Session is a session with its ID, as sessions are served to clients
+type Session struct {
+	ID string `json:"id"`
+	*ThoughtHistory
+}
+
+// SessionMarkdown renders a session as a Markdown document
+func SessionMarkdown(id string, history *ThoughtHistory) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", id)
+	fmt.Fprintf(&b, "Started %s\n", history.CreatedAt.UTC().Format("2006-01-02 15:04:05 MST"))
+
+	for _, thought := range history.Thoughts {
+		heading := fmt.Sprintf("Thought %d/%d", thought.ThoughtNumber, thought.TotalThoughts)
+		switch {
+		case thought.IsRevision:
+			heading += fmt.Sprintf(" (revises thought %d)", thought.RevisesThought)
+		case thought.BranchID != "":
+			heading += fmt.Sprintf(" (branch %s from thought %d)", thought.BranchID, thought.BranchFromThought)
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", heading, thought.Thought)
+		if thought.NeedsMoreThoughts {
+			b.WriteString("\n_More thoughts needed._\n")
+		}
+	}
+
+	return b.String()
+}
+
+// thoughtMeta is the machine-readable part of a successful tool result,
+// returned in _meta next to the text for clients that parse responses
+func thoughtMeta(sessionID string, req *ThoughtRequest, history *ThoughtHistory) map[string]any {
+	branches := []string{}
+	historyLength := 0
+	if history != nil {
+		for id := range history.Branches {
+			branches = append(branches, id)
+		}
+		sort.Strings(branches)
+		historyLength = len(history.Thoughts)
+	}
+
+	return map[string]any{
+		"sessionId":            sessionID,
+		"thoughtNumber":        req.ThoughtNumber,
+		"totalThoughts":        req.TotalThoughts,
+		"nextThoughtNeeded":    req.NextThoughtNeeded,
+		"branches":             branches,
+		"thoughtHistoryLength": historyLength,
+	}
+}
+
+// sessionResourceTemplates describe the session resources served by SessionResource
+func sessionResourceTemplates() []mcp.ResourceTemplate {
+	return []mcp.ResourceTemplate{
+		mcp.NewResourceTemplate(sessionURITemplate, "session",
+			mcp.WithTemplateDescription("A thinking session with all its thoughts and branches"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		mcp.NewResourceTemplate(sessionExportURITemplate, "session-export",
+			mcp.WithTemplateDescription("A thinking session exported as json or markdown"),
+		),
+	}
+}
+
+// SessionResource reads a session of the caller's tenant, either as JSON or,
+// under /export, in the requested export format
+func (s *SequentialThinkingServer) SessionResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := request.Params.URI
+	u, err := url.Parse(uri)
+	if err != nil || !strings.HasPrefix(uri, sessionURIPrefix) {
+		return nil, fmt.Errorf("invalid session URI %q", uri)
+	}
+	escaped, export := strings.CutSuffix(strings.TrimPrefix(u.EscapedPath(), "/"), "/export")
+	sessionID, err := url.PathUnescape(escaped)
+	if err != nil || sessionID == "" || strings.Contains(escaped, "/") {
+		return nil, fmt.Errorf("invalid session URI %q", uri)
+	}
+
+	// Sessions of other subjects read as missing, as they do over HTTP
+	if !OwnsSession(ctx, sessionID) {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	tenantID, err := TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.tenants.get(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	history, ok := t.store.Get(sessionID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	format := "json"
+	if export {
+		if f := u.Query().Get("format"); f != "" {
+			format = f
+		}
+	}
+	exported, ok := ExportFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %q, use json or markdown", format)
+	}
+
+	text := SessionMarkdown(sessionID, history)
+	if format == "json" {
+		data, err := json.Marshal(Session{ID: sessionID, ThoughtHistory: history})
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: uri, MIMEType: exported.ContentType, Text: text},
+	}, nil
+}
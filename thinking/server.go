@@ -0,0 +1,696 @@
+// Package thinking implements the sequentialthinking MCP tool: thought
+// validation, per-tenant session stores, rate limits, events, webhooks and
+// the call log. Register adds the tool to any mcp-go server:
+//
+//	mcpServer := server.NewMCPServer("my-server", "1.0.0")
+//	thinker := thinking.Register(mcpServer, thinking.WithRateLimit(thinking.RateLimitConfig{Rate: 5, Burst: 10}))
+//	defer thinker.Shutdown(context.Background())
+package thinking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ThoughtRequest represents the input parameters for the sequential thinking tool
+type ThoughtRequest struct {
+	Thought           string `json:"thought"`
+	NextThoughtNeeded bool   `json:"nextThoughtNeeded"`
+	ThoughtNumber     int    `json:"thoughtNumber"`
+	TotalThoughts     int    `json:"totalThoughts"`
+	IsRevision        bool   `json:"isRevision,omitempty"`
+	RevisesThought    int    `json:"revisesThought,omitempty"`
+	BranchFromThought int    `json:"branchFromThought,omitempty"`
+	BranchID          string `json:"branchId,omitempty"`
+	NeedsMoreThoughts bool   `json:"needsMoreThoughts,omitempty"`
+}
+
+// ThoughtHistory stores the chain of thoughts
+type ThoughtHistory struct {
+	Thoughts  []ThoughtRequest `json:"thoughts"`
+	Branches  map[string][]int `json:"branches,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// ToolName is the name the sequentialthinking tool is registered under
+const ToolName = "sequentialthinking"
+
+// errShuttingDown is returned for tool calls that arrive after shutdown has started
+var errShuttingDown = errors.New("server is shutting down")
+
+// SequentialThinkingServer implements the MCP server for sequential thinking
+type SequentialThinkingServer struct {
+	tenants *tenantRegistry
+	metrics *metrics
+	logger  *slog.Logger
+	// events announces every stored thought to in-process subscribers
+	events *eventBus
+	// webhooks forwards events to the receivers in webhookCfg; nil when it has no URLs
+	webhookCfg WebhookConfig
+	webhooks   *webhookDispatcher
+	// callLog records every tool call, accepted or not; nil disables it
+	callLog CallLog
+	// middleware wraps handleThought, outermost first; handle is the chain
+	middleware []Middleware
+	handle     Handler
+	// now is the clock behind session IDs, session creation times, rate
+	// limits, events and call records; replays substitute recorded times
+	now func() time.Time
+	// newSessionID names the session a call at the given time belongs to
+	newSessionID func(now time.Time) string
+
+	// redactThoughts keeps thought text out of the logs
+	redactThoughts bool
+
+	// inflight tracks running CallTool invocations so shutdown can drain them
+	inflight sync.WaitGroup
+	mu       sync.RWMutex
+	closing  bool
+	// draining fails readiness while tool calls are still served
+	draining bool
+	// logClosed is set once Shutdown has closed the call log
+	logClosed bool
+}
+
+// Option configures a SequentialThinkingServer
+type Option func(*SequentialThinkingServer)
+
+// WithLogger sets the structured logger used for per-call logging
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *SequentialThinkingServer) {
+		s.logger = logger
+	}
+}
+
+// WithThoughtRedaction replaces thought text in logs with its length
+func WithThoughtRedaction(redact bool) Option {
+	return func(s *SequentialThinkingServer) {
+		s.redactThoughts = redact
+	}
+}
+
+// WithRateLimit enables per-client rate limiting and daily thought quotas
+func WithRateLimit(cfg RateLimitConfig) Option {
+	return func(s *SequentialThinkingServer) {
+		s.tenants.limits = cfg
+	}
+}
+
+// WithTenantLimits overrides the rate limits of individual tenants
+func WithTenantLimits(limits map[string]RateLimitConfig) Option {
+	return func(s *SequentialThinkingServer) {
+		s.tenants.overrides = limits
+	}
+}
+
+// WithMaxTenants caps the number of tenants created on demand, for example
+// from the tenants callers name. The default tenant and tenants with limits
+// of their own from WithTenantLimits do not count. 0, the default, means no
+// cap.
+func WithMaxTenants(n int) Option {
+	return func(s *SequentialThinkingServer) {
+		s.tenants.max = n
+	}
+}
+
+// WithWebhooks posts session events to the configured URLs
+func WithWebhooks(cfg WebhookConfig) Option {
+	return func(s *SequentialThinkingServer) {
+		s.webhookCfg = cfg
+	}
+}
+
+// WithCallLog records every tool call in log. The server closes log on shutdown.
+func WithCallLog(log CallLog) Option {
+	return func(s *SequentialThinkingServer) {
+		s.callLog = log
+	}
+}
+
+// WithClock sets the clock that dates session IDs, sessions, events and call
+// records and drives rate limits and quotas, so tests can use a fake one
+func WithClock(now func() time.Time) Option {
+	return func(s *SequentialThinkingServer) {
+		s.now = now
+	}
+}
+
+// WithStore makes each tenant keep its sessions in the store newStore returns
+// for it, instead of in memory. The server closes the stores on shutdown.
+func WithStore(newStore func(tenantID string) SessionStore) Option {
+	return func(s *SequentialThinkingServer) {
+		s.tenants.newStore = newStore
+	}
+}
+
+// WithIDGenerator sets how sessions are named: calls for which newID returns
+// the same ID share a session. The ID is still prefixed with the caller's
+// subject, so principals never share sessions. The default names sessions
+// after the second of the call, session_<unix time>.
+func WithIDGenerator(newID func(now time.Time) string) Option {
+	return func(s *SequentialThinkingServer) {
+		s.newSessionID = newID
+	}
+}
+
+// timestampSessionID is the default session ID: one session per second
+func timestampSessionID(now time.Time) string {
+	return fmt.Sprintf("session_%d", now.Unix())
+}
+
+// NewSequentialThinkingServer creates a new sequential thinking server
+func NewSequentialThinkingServer(opts ...Option) *SequentialThinkingServer {
+	s := &SequentialThinkingServer{
+		logger:       slog.Default(),
+		events:       newEventBus(),
+		now:          time.Now,
+		newSessionID: timestampSessionID,
+	}
+	// Both read s.now when tenants are created, after the options have run
+	s.tenants = newTenantRegistry(
+		func(string) SessionStore { return newMemoryStore(s.now) },
+		func() time.Time { return s.now() },
+	)
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.handle = s.chain()
+	s.tenants.create(DefaultTenant)
+	s.metrics = newMetrics(func() float64 {
+		sessions := 0
+		for _, t := range s.tenants.all() {
+			sessions += len(t.store.List())
+		}
+		return float64(sessions)
+	})
+	s.events.dropped = s.metrics.eventsDropped.Inc
+	if len(s.webhookCfg.URLs) > 0 {
+		s.webhooks = newWebhookDispatcher(s.webhookCfg, s.events, s.logger, func(outcome string) {
+			s.metrics.webhookDeliveries.WithLabelValues(outcome).Inc()
+		})
+	}
+
+	return s
+}
+
+// begin registers an in-flight call, refusing it once shutdown has started
+func (s *SequentialThinkingServer) begin() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closing {
+		return false
+	}
+	s.inflight.Add(1)
+
+	return true
+}
+
+// Ready reports whether the server can accept tool calls
+func (s *SequentialThinkingServer) Ready() error {
+	s.mu.RLock()
+	closing := s.closing || s.draining
+	s.mu.RUnlock()
+
+	if closing {
+		return errShuttingDown
+	}
+
+	for _, t := range s.tenants.all() {
+		if err := t.store.Ping(); err != nil {
+			return fmt.Errorf("tenant %s: %w", t.id, err)
+		}
+	}
+
+	return nil
+}
+
+// Drain makes Ready fail while tool calls are still served, so load
+// balancers stop sending new clients before connections are closed. Call it
+// when shutdown begins, ahead of Shutdown.
+func (s *SequentialThinkingServer) Drain() {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+}
+
+// Shutdown stops accepting new tool calls, waits for in-flight calls to finish,
+// delivers queued webhooks and flushes the session store. If ctx expires
+// before the calls drain, or the call log or a store fails to close, the
+// remaining stores are still flushed and all errors are returned joined.
+func (s *SequentialThinkingServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+
+	var drainErr error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		drainErr = fmt.Errorf("draining in-flight calls: %w", ctx.Err())
+	}
+
+	if s.webhooks != nil {
+		if err := s.webhooks.close(ctx); err != nil && drainErr == nil {
+			drainErr = err
+		}
+	}
+
+	errs := []error{drainErr}
+	if s.callLog != nil {
+		s.mu.Lock()
+		s.logClosed = true
+		s.mu.Unlock()
+		if err := s.callLog.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing call log: %w", err))
+		}
+	}
+	for _, t := range s.tenants.all() {
+		if err := t.store.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("flushing session store of tenant %s: %w", t.id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ListTools returns the available tools
+func (s *SequentialThinkingServer) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	return []mcp.Tool{
+		{
+			Name:        ToolName,
+			Description: "A detailed tool for dynamic and reflective problem-solving through thoughts.\nThis tool helps analyze problems through a flexible thinking process that can adapt and evolve.\nEach thought can build on, question, or revise previous insights as understanding deepens.",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"thought": map[string]interface{}{
+						"type":        "string",
+						"description": "Your current thinking step",
+					},
+					"nextThoughtNeeded": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether another thought step is needed",
+					},
+					"thoughtNumber": map[string]interface{}{
+						"type":        "integer",
+						"minimum":     1,
+						"description": "Current thought number",
+					},
+					"totalThoughts": map[string]interface{}{
+						"type":        "integer",
+						"minimum":     1,
+						"description": "Estimated total thoughts needed",
+					},
+					"isRevision": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether this revises previous thinking",
+					},
+					"revisesThought": map[string]interface{}{
+						"type":        "integer",
+						"minimum":     1,
+						"description": "Which thought is being reconsidered",
+					},
+					"branchFromThought": map[string]interface{}{
+						"type":        "integer",
+						"minimum":     1,
+						"description": "Branching point thought number",
+					},
+					"branchId": map[string]interface{}{
+						"type":        "string",
+						"description": "Branch identifier",
+					},
+					"needsMoreThoughts": map[string]interface{}{
+						"type":        "boolean",
+						"description": "If more thoughts are needed",
+					},
+				},
+				Required: []string{"thought", "nextThoughtNeeded", "thoughtNumber", "totalThoughts"},
+			},
+		},
+	}, nil
+}
+
+// CallTool handles tool execution
+func (s *SequentialThinkingServer) CallTool(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+	start := time.Now()
+	now := s.now()
+	outcome := OutcomeError
+	var req ThoughtRequest
+	var tenantID, sessionID string
+	// admitted is set once the call holds an in-flight slot, which it keeps
+	// until it is recorded so Shutdown cannot close the call log first
+	admitted := false
+	_, span := tracer.Start(ctx, "sequentialthinking.CallTool")
+	defer func() {
+		latency := time.Since(start)
+		s.metrics.observeCall(outcome, latency)
+		s.logCall(sessionID, &req, outcome, latency, err)
+		s.recordCall(ctx, now, request, tenantID, sessionID, &req, outcome, result, err)
+		span.SetAttributes(attribute.String("sequentialthinking.outcome", outcome))
+		endSpan(span, err)
+		if admitted {
+			s.inflight.Done()
+		}
+	}()
+
+	if request.Params.Name != ToolName {
+		outcome = OutcomeUnknownTool
+		return nil, fmt.Errorf("unknown tool: %s", request.Params.Name)
+	}
+
+	tenantID, err = TenantFromContext(ctx)
+	if err != nil {
+		outcome = OutcomeInvalidArgs
+		return nil, err
+	}
+	t, err := s.tenants.get(tenantID)
+	if err != nil {
+		outcome = OutcomeInvalidArgs
+		return nil, err
+	}
+	t.calls.Add(1)
+
+	if t.limiter != nil {
+		key := clientKey(ctx)
+		if retryAfter, err := t.limiter.allow(key); err != nil {
+			t.rejected.Add(1)
+			outcome = OutcomeRateLimited
+			return rateLimitedResult(err, retryAfter), nil
+		}
+		// The daily quota counts stored thoughts, not calls
+		defer func() {
+			if outcome != OutcomeSuccess {
+				t.limiter.refund(key)
+			}
+		}()
+	}
+
+	if !s.begin() {
+		outcome = OutcomeUnavailable
+		return nil, errShuttingDown
+	}
+	admitted = true
+
+	if req, err = decodeThoughtArguments(request.Params.Arguments); err != nil {
+		outcome = OutcomeInvalidArgs
+		return nil, err
+	}
+
+	sessionID = scopedSessionID(ctx, s.newSessionID(now))
+	call := &ToolCall{
+		Request:   req,
+		tenant:    t.id,
+		SessionID: sessionID,
+		Time:      now,
+		Raw:       request,
+		store:     t.store,
+	}
+	result, err = s.handle(ctx, call)
+	// The call log records the thought as handled, so a thought redacted by
+	// middleware does not reach it in the clear
+	if call.Request != req {
+		request.Params.Arguments = call.Request
+	}
+	req, sessionID = call.Request, call.SessionID
+	span.SetAttributes(thoughtAttributes(sessionID, &req)...)
+	// The outcome is the thought handler's, unless middleware answered first
+	outcome = call.outcome
+	if outcome == "" {
+		outcome = OutcomeRejected
+	}
+
+	return result, err
+}
+
+// handleThought validates and stores the thought of a call, at the end of
+// the middleware chain
+func (s *SequentialThinkingServer) handleThought(ctx context.Context, call *ToolCall) (*mcp.CallToolResult, error) {
+	req, sessionID := &call.Request, call.SessionID
+	call.outcome = OutcomeError
+
+	// Middleware may move the call to another session, but not to one of
+	// another subject
+	if !OwnsSession(ctx, sessionID) {
+		return nil, fmt.Errorf("session %s does not belong to the caller", sessionID)
+	}
+
+	// Validate input
+	if err := s.validateThoughtRequest(req); err != nil {
+		call.outcome = OutcomeValidationError
+		var verr *validationError
+		if errors.As(err, &verr) {
+			s.metrics.validationFailures.WithLabelValues(verr.Reason).Inc()
+		}
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	// Process the thought
+	if err := call.store.Append(sessionID, *req); err != nil {
+		return nil, fmt.Errorf("failed to store thought: %w", err)
+	}
+	history, ok := call.store.Get(sessionID)
+	if ok {
+		s.metrics.observeThought(req, history)
+		s.events.publish(sessionEvents(call.tenant, sessionID, req, history, call.Time)...)
+	}
+
+	// Format response
+	response := s.formatThoughtResponse(req, history)
+	call.outcome = OutcomeSuccess
+
+	return &mcp.CallToolResult{
+		Result: mcp.Result{Meta: thoughtMeta(sessionID, req, history)},
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: response,
+			},
+		},
+	}, nil
+}
+
+// decodeThoughtArguments converts tool arguments into a ThoughtRequest.
+// Arguments normally arrive as the map mcp-go decodes JSON into; anything
+// else is round-tripped through JSON into such a map first, so both forms
+// decode alike. Fields of the wrong type are ignored, leaving them for
+// validation to reject.
+func decodeThoughtArguments(arguments any) (ThoughtRequest, error) {
+	var req ThoughtRequest
+
+	args, ok := arguments.(map[string]interface{})
+	if !ok {
+		argsBytes, err := json.Marshal(arguments)
+		if err != nil {
+			return req, fmt.Errorf("failed to marshal arguments: %w", err)
+		}
+		if err := json.Unmarshal(argsBytes, &args); err != nil {
+			return req, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	if thought, ok := args["thought"].(string); ok {
+		req.Thought = thought
+	}
+	if next, ok := args["nextThoughtNeeded"].(bool); ok {
+		req.NextThoughtNeeded = next
+	}
+	if number, ok := intArgument(args["thoughtNumber"]); ok {
+		req.ThoughtNumber = number
+	}
+	if total, ok := intArgument(args["totalThoughts"]); ok {
+		req.TotalThoughts = total
+	}
+	if isRevision, ok := args["isRevision"].(bool); ok {
+		req.IsRevision = isRevision
+	}
+	if revises, ok := intArgument(args["revisesThought"]); ok {
+		req.RevisesThought = revises
+	}
+	if branchFrom, ok := intArgument(args["branchFromThought"]); ok {
+		req.BranchFromThought = branchFrom
+	}
+	if branchID, ok := args["branchId"].(string); ok {
+		req.BranchID = branchID
+	}
+	if needsMore, ok := args["needsMoreThoughts"].(bool); ok {
+		req.NeedsMoreThoughts = needsMore
+	}
+
+	return req, nil
+}
+
+// maxIntArgument bounds numeric arguments to values a float64 holds exactly
+const maxIntArgument = 1 << 53
+
+// intArgument truncates a JSON number to an int. Numbers beyond
+// ±maxIntArgument are ignored like values of the wrong type, since
+// converting them is not portable.
+func intArgument(value interface{}) (int, bool) {
+	number, ok := value.(float64)
+	if !ok || number < -maxIntArgument || number > maxIntArgument {
+		return 0, false
+	}
+
+	return int(number), true
+}
+
+// validationError describes why a thought request was rejected. Reason is a
+// stable, low-cardinality identifier suitable for metric labels.
+type validationError struct {
+	Reason  string
+	Message string
+}
+
+func (e *validationError) Error() string {
+	return e.Message
+}
+
+// validateThoughtRequest validates the thought request parameters
+func (s *SequentialThinkingServer) validateThoughtRequest(req *ThoughtRequest) error {
+	if req.Thought == "" {
+		return &validationError{"empty_thought", "thought cannot be empty"}
+	}
+	if req.ThoughtNumber < 1 {
+		return &validationError{"invalid_thought_number", "thought number must be positive"}
+	}
+	if req.TotalThoughts < 1 {
+		return &validationError{"invalid_total_thoughts", "total thoughts must be positive"}
+	}
+	if req.ThoughtNumber > req.TotalThoughts && !req.NeedsMoreThoughts {
+		return &validationError{"exceeds_total_thoughts", "thought number cannot exceed total thoughts unless more thoughts are needed"}
+	}
+	if req.IsRevision && req.RevisesThought < 1 {
+		return &validationError{"missing_revises_thought", "revises thought must be specified for revisions"}
+	}
+	return nil
+}
+
+// formatThoughtResponse formats the response for a thought; history is the
+// session the thought was stored in and may be nil
+func (s *SequentialThinkingServer) formatThoughtResponse(req *ThoughtRequest, history *ThoughtHistory) string {
+	response := fmt.Sprintf("🤔 **Thought %d/%d**", req.ThoughtNumber, req.TotalThoughts)
+
+	if req.IsRevision {
+		response += fmt.Sprintf(" (Revision of Thought %d)", req.RevisesThought)
+	}
+
+	if req.BranchID != "" {
+		response += fmt.Sprintf(" [Branch: %s]", req.BranchID)
+	}
+
+	response += fmt.Sprintf("\n\n%s", req.Thought)
+
+	if req.NextThoughtNeeded {
+		response += "\n\n*Continuing to next thought...*"
+	} else {
+		response += "\n\n✅ **Thinking process completed**"
+
+		// Add summary of the thinking process
+		if history != nil && len(history.Thoughts) > 1 {
+			response += fmt.Sprintf("\n\n📊 **Summary**: Completed %d thoughts", len(history.Thoughts))
+
+			if len(history.Branches) > 0 {
+				response += fmt.Sprintf(" across %d branches", len(history.Branches))
+			}
+		}
+	}
+
+	if req.NeedsMoreThoughts {
+		response += "\n\n🔄 **Note**: Additional thoughts may be needed to fully explore this problem."
+	}
+
+	return response
+}
+
+// ListResources returns the available resources (none for this server)
+func (s *SequentialThinkingServer) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	return []mcp.Resource{}, nil
+}
+
+// ReadResource reads a session resource of the caller's tenant
+func (s *SequentialThinkingServer) ReadResource(ctx context.Context, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	contents, err := s.SessionResource(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.ReadResourceResult{Contents: contents}, nil
+}
+
+// ListPrompts returns the available prompts (none for this server)
+func (s *SequentialThinkingServer) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	return []mcp.Prompt{}, nil
+}
+
+// GetPrompt gets a prompt (not implemented for this server)
+func (s *SequentialThinkingServer) GetPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return nil, fmt.Errorf("no prompts available")
+}
+
+// Register creates a SequentialThinkingServer configured by opts and adds
+// its tool and session resources to mcpServer. The caller serves mcpServer
+// over any transport and calls Shutdown on the returned server when done.
+func Register(mcpServer *server.MCPServer, opts ...Option) *SequentialThinkingServer {
+	s := NewSequentialThinkingServer(opts...)
+
+	// Add the sequential thinking tool
+	mcpServer.AddTool(
+		mcp.NewTool(ToolName,
+			mcp.WithDescription("A detailed tool for dynamic and reflective problem-solving through thoughts.\nThis tool helps analyze problems through a flexible thinking process that can adapt and evolve.\nEach thought can build on, question, or revise previous insights as understanding deepens."),
+			mcp.WithString("thought",
+				mcp.Description("Your current thinking step"),
+				mcp.Required(),
+			),
+			mcp.WithBoolean("nextThoughtNeeded",
+				mcp.Description("Whether another thought step is needed"),
+				mcp.Required(),
+			),
+			mcp.WithNumber("thoughtNumber",
+				mcp.Description("Current thought number"),
+				mcp.Required(),
+			),
+			mcp.WithNumber("totalThoughts",
+				mcp.Description("Estimated total thoughts needed"),
+				mcp.Required(),
+			),
+			mcp.WithBoolean("isRevision",
+				mcp.Description("Whether this revises previous thinking"),
+			),
+			mcp.WithNumber("revisesThought",
+				mcp.Description("Which thought is being reconsidered"),
+			),
+			mcp.WithNumber("branchFromThought",
+				mcp.Description("Branching point thought number"),
+			),
+			mcp.WithString("branchId",
+				mcp.Description("Branch identifier"),
+			),
+			mcp.WithBoolean("needsMoreThoughts",
+				mcp.Description("If more thoughts are needed"),
+			),
+		),
+		s.CallTool,
+	)
+
+	// Session history and exports, addressed by session ID
+	for _, template := range sessionResourceTemplates() {
+		mcpServer.AddResourceTemplate(template, s.SessionResource)
+	}
+
+	return s
+}
@@ -1,8 +1,13 @@
-package main
+package thinking
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -274,7 +279,7 @@ func TestFormatThoughtResponse(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			response := server.formatThoughtResponse(&tt.req, "test-session")
+			response := server.formatThoughtResponse(&tt.req, nil)
 
 			for _, expected := range tt.contains {
 				if !contains(response, expected) {
@@ -322,7 +327,9 @@ func TestBranchingLogic(t *testing.T) {
 
 	// Check that the branch was recorded
 	sessionFound := false
-	for _, history := range server.history {
+	store := server.tenants.create(DefaultTenant).store
+	for _, id := range store.List() {
+		history, _ := store.Get(id)
 		if len(history.Branches) > 0 {
 			if branch, exists := history.Branches["alternative"]; exists {
 				if len(branch) == 1 && branch[0] == 2 {
@@ -338,7 +345,162 @@ func TestBranchingLogic(t *testing.T) {
 	}
 }
 
-// Helper function to check if string contains substring
+func TestShutdown(t *testing.T) {
+	server := NewSequentialThinkingServer()
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	toolRequest := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "sequentialthinking",
+			Arguments: map[string]interface{}{
+				"thought":           "Arrives too late",
+				"nextThoughtNeeded": false,
+				"thoughtNumber":     float64(1),
+				"totalThoughts":     float64(1),
+			},
+		},
+	}
+
+	if _, err := server.CallTool(context.Background(), toolRequest); !errors.Is(err, errShuttingDown) {
+		t.Errorf("Expected errShuttingDown after shutdown, got %v", err)
+	}
+}
+
+func TestShutdownWaitsForInFlightCalls(t *testing.T) {
+	server := NewSequentialThinkingServer()
+
+	if !server.begin() {
+		t.Fatal("begin refused a call before shutdown")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline error while a call is in flight, got %v", err)
+	}
+
+	server.inflight.Done()
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown after drain failed: %v", err)
+	}
+}
+
+func TestServerInstancesAreIsolated(t *testing.T) {
+	strict := NewSequentialThinkingServer(WithRateLimit(RateLimitConfig{DailyQuota: 1}))
+	lenient := NewSequentialThinkingServer()
+
+	for i := 1; i <= 2; i++ {
+		result, err := lenient.CallTool(context.Background(), thoughtCall(i, true))
+		if err != nil || result.IsError {
+			t.Fatalf("Call %d to the unlimited server failed: %v %v", i, err, result)
+		}
+	}
+	if result, _ := strict.CallTool(context.Background(), thoughtCall(1, true)); result.IsError {
+		t.Fatal("First call to the limited server was refused")
+	}
+	if result, _ := strict.CallTool(context.Background(), thoughtCall(2, true)); !result.IsError {
+		t.Error("Expected the limited server to enforce its quota")
+	}
+
+	if sessions := strict.tenants.create(DefaultTenant).store.List(); len(sessions) != 1 {
+		t.Fatalf("Expected 1 session in the limited server, got %v", sessions)
+	}
+	history, _ := strict.tenants.create(DefaultTenant).store.Get(strict.tenants.create(DefaultTenant).store.List()[0])
+	if len(history.Thoughts) != 1 {
+		t.Errorf("Expected the servers not to share sessions, got %d thoughts", len(history.Thoughts))
+	}
+}
+
+func TestWithStore(t *testing.T) {
+	stores := make(map[string]*memoryStore)
+	server := NewSequentialThinkingServer(WithStore(func(tenantID string) SessionStore {
+		stores[tenantID] = newMemoryStore(time.Now)
+		return stores[tenantID]
+	}))
+
+	ctx := ContextWithTenant(context.Background(), "acme")
+	if _, err := server.CallTool(ctx, thoughtCall(1, true)); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+
+	if _, ok := stores[DefaultTenant]; !ok {
+		t.Error("Expected a store for the default tenant")
+	}
+	if sessions := stores["acme"].List(); len(sessions) != 1 {
+		t.Errorf("Expected the call in the acme store, got sessions %v", sessions)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := stores["acme"].Ping(); !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("Expected injected stores to be closed on shutdown, got %v", err)
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	server := NewSequentialThinkingServer(WithIDGenerator(func(time.Time) string { return "fixed" }))
+	alice := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice"})
+
+	for i := 1; i <= 2; i++ {
+		if _, err := server.CallTool(alice, thoughtCall(i, true)); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+	}
+
+	history, ok := server.tenants.create(DefaultTenant).store.Get("alice/fixed")
+	if !ok {
+		t.Fatalf("Expected session alice/fixed, got %v", server.tenants.create(DefaultTenant).store.List())
+	}
+	if len(history.Thoughts) != 2 {
+		t.Errorf("Expected both calls in the generated session, got %d thoughts", len(history.Thoughts))
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)
+	server := NewSequentialThinkingServer(
+		WithClock(func() time.Time { return now }),
+		WithRateLimit(RateLimitConfig{DailyQuota: 1}),
+	)
+
+	if result, _ := server.CallTool(context.Background(), thoughtCall(1, true)); result.IsError {
+		t.Fatal("First call was refused")
+	}
+	history, ok := server.tenants.create(DefaultTenant).store.Get(fmt.Sprintf("session_%d", now.Unix()))
+	if !ok {
+		t.Fatalf("Expected a session named after the fake clock, got %v", server.tenants.create(DefaultTenant).store.List())
+	}
+	if !history.CreatedAt.Equal(now) {
+		t.Errorf("Expected CreatedAt %v, got %v", now, history.CreatedAt)
+	}
+
+	if result, _ := server.CallTool(context.Background(), thoughtCall(2, true)); !result.IsError {
+		t.Error("Expected the daily quota to be exhausted")
+	}
+	now = now.Add(time.Hour)
+	if result, _ := server.CallTool(context.Background(), thoughtCall(2, true)); result.IsError {
+		t.Error("Expected the quota to reset at midnight on the fake clock")
+	}
+}
+
+// writeTempFile writes content to a file in a test's temporary directory
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+
+	return path
+}
+
+// contains reports whether substr is within s
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) &&
 		(s == substr ||
@@ -0,0 +1,191 @@
+package thinking
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SessionStore keeps the thought history of every session
+type SessionStore interface {
+	// Append records a thought in the session, creating the session if needed
+	Append(sessionID string, req ThoughtRequest) error
+	// Get returns a copy of the session history
+	Get(sessionID string) (*ThoughtHistory, bool)
+	// List returns the IDs of all known sessions
+	List() []string
+	// Delete removes a session; it returns ErrSessionNotFound for unknown IDs
+	Delete(sessionID string) error
+	// Ping reports whether the store can currently serve reads and writes
+	Ping() error
+	// Close flushes any pending state; the store must not be used afterwards
+	Close() error
+}
+
+var (
+	// ErrStoreClosed is returned by a store that has already been closed
+	ErrStoreClosed = errors.New("session store is closed")
+	// ErrSessionNotFound is returned when deleting a session that does not exist
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// memoryStore is a SessionStore that keeps everything in process memory
+type memoryStore struct {
+	// now dates new sessions
+	now func() time.Time
+
+	mu       sync.RWMutex
+	sessions map[string]*ThoughtHistory
+	closed   bool
+}
+
+// newMemoryStore creates an empty in-memory session store whose sessions
+// are dated by now
+func newMemoryStore(now func() time.Time) *memoryStore {
+	return &memoryStore{
+		now:      now,
+		sessions: make(map[string]*ThoughtHistory),
+	}
+}
+
+// Append records a thought and its branch membership
+func (m *memoryStore) Append(sessionID string, req ThoughtRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStoreClosed
+	}
+
+	history := m.sessions[sessionID]
+	if history == nil {
+		history = &ThoughtHistory{
+			Thoughts:  []ThoughtRequest{},
+			Branches:  make(map[string][]int),
+			CreatedAt: m.now(),
+		}
+		m.sessions[sessionID] = history
+	}
+
+	history.Thoughts = append(history.Thoughts, req)
+
+	if req.BranchID != "" {
+		history.Branches[req.BranchID] = append(history.Branches[req.BranchID], req.ThoughtNumber)
+	}
+
+	return nil
+}
+
+// Get returns a deep copy of the session history so callers never race with writers
+func (m *memoryStore) Get(sessionID string) (*ThoughtHistory, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history, ok := m.sessions[sessionID]
+	if !ok {
+		return nil, false
+	}
+
+	return history.clone(), true
+}
+
+// List returns the session IDs in sorted order
+func (m *memoryStore) List() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.sessions))
+	for id := range m.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Delete removes the session and its history
+func (m *memoryStore) Delete(sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStoreClosed
+	}
+	if _, ok := m.sessions[sessionID]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(m.sessions, sessionID)
+
+	return nil
+}
+
+// Ping fails once the store has been closed
+func (m *memoryStore) Ping() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return ErrStoreClosed
+	}
+
+	return nil
+}
+
+// Close marks the store closed; an in-memory store has nothing to persist
+func (m *memoryStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+
+	return nil
+}
+
+// sessionRestorer is implemented by stores that can load a whole session,
+// keeping its original creation time
+type sessionRestorer interface {
+	restore(sessionID string, history *ThoughtHistory) error
+}
+
+// restoreSession loads history into store, replacing any session with the
+// same ID. Stores that cannot restore sessions get the thoughts appended.
+func restoreSession(store SessionStore, sessionID string, history *ThoughtHistory) error {
+	if r, ok := store.(sessionRestorer); ok {
+		return r.restore(sessionID, history)
+	}
+	for _, thought := range history.Thoughts {
+		if err := store.Append(sessionID, thought); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restore stores a copy of history under sessionID
+func (m *memoryStore) restore(sessionID string, history *ThoughtHistory) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStoreClosed
+	}
+	m.sessions[sessionID] = history.clone()
+
+	return nil
+}
+
+// clone returns a deep copy of the history
+func (h *ThoughtHistory) clone() *ThoughtHistory {
+	c := &ThoughtHistory{
+		Thoughts:  append([]ThoughtRequest(nil), h.Thoughts...),
+		Branches:  make(map[string][]int, len(h.Branches)),
+		CreatedAt: h.CreatedAt,
+	}
+	for id, thoughts := range h.Branches {
+		c.Branches[id] = append([]int(nil), thoughts...)
+	}
+
+	return c
+}
@@ -0,0 +1,259 @@
+package thinking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTenant owns every call that carries no tenant, such as stdio sessions
+const DefaultTenant = "default"
+
+// validTenantID limits tenant IDs to a safe, log- and URL-friendly alphabet
+var validTenantID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ErrTooManyTenants is returned for calls that would create a tenant beyond
+// the limit set with WithMaxTenants
+var ErrTooManyTenants = errors.New("too many tenants")
+
+// tenant is the state partitioned per tenant: its own session store and its
+// rate limiter. Tenants with their own limits get their own limiter; all
+// others share one, so a client cannot reset its limits by switching
+// tenants.
+type tenant struct {
+	id      string
+	store   SessionStore
+	limiter *rateLimiter
+
+	calls    atomic.Int64
+	rejected atomic.Int64
+}
+
+// TenantUsage summarises one tenant
+type TenantUsage struct {
+	Tenant   string `json:"tenant"`
+	Sessions int    `json:"sessions"`
+	Thoughts int    `json:"thoughts"`
+	Calls    int64  `json:"calls"`
+	Rejected int64  `json:"rejected"`
+}
+
+// tenantRegistry creates tenants on first use
+type tenantRegistry struct {
+	newStore func(tenantID string) SessionStore
+	// now is the clock of the tenants' rate limiters
+	now func() time.Time
+	// limits applies to tenants without an entry in overrides, through the
+	// one limiter they share
+	limits    RateLimitConfig
+	overrides map[string]RateLimitConfig
+	// max caps the tenants created on demand, beyond the default tenant and
+	// those in overrides; 0 means no cap
+	max int
+
+	mu        sync.RWMutex
+	tenants   map[string]*tenant
+	onDemand  int
+	shared    *rateLimiter
+	sharedSet bool
+}
+
+// newTenantRegistry creates a registry whose tenants get stores from
+// newStore and rate limiters running on now
+func newTenantRegistry(newStore func(tenantID string) SessionStore, now func() time.Time) *tenantRegistry {
+	return &tenantRegistry{
+		newStore: newStore,
+		now:      now,
+		tenants:  make(map[string]*tenant),
+	}
+}
+
+// get returns the tenant with the given ID, creating it if needed. Callers
+// can only create tenants up to the cap, unless the tenant is declared.
+func (r *tenantRegistry) get(id string) (*tenant, error) {
+	return r.lookup(id, true)
+}
+
+// create returns the tenant with the given ID, creating it regardless of the
+// cap; it is meant for tenants the server itself knows about
+func (r *tenantRegistry) create(id string) *tenant {
+	t, _ := r.lookup(id, false)
+	return t
+}
+
+// lookup returns or creates the tenant, enforcing the cap when capped
+func (r *tenantRegistry) lookup(id string, capped bool) (*tenant, error) {
+	r.mu.RLock()
+	t := r.tenants[id]
+	r.mu.RUnlock()
+	if t != nil {
+		return t, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t := r.tenants[id]; t != nil {
+		return t, nil
+	}
+
+	limits, declared := r.overrides[id]
+	if !declared && id != DefaultTenant {
+		if capped && r.max > 0 && r.onDemand >= r.max {
+			return nil, fmt.Errorf("%w: cannot create tenant %q", ErrTooManyTenants, id)
+		}
+		r.onDemand++
+	}
+
+	t = &tenant{
+		id:    id,
+		store: r.newStore(id),
+	}
+	if declared {
+		t.limiter = newRateLimiter(limits, r.now)
+	} else {
+		if !r.sharedSet {
+			r.shared = newRateLimiter(r.limits, r.now)
+			r.sharedSet = true
+		}
+		t.limiter = r.shared
+	}
+	r.tenants[id] = t
+
+	return t, nil
+}
+
+// all returns every tenant created so far, ordered by ID
+func (r *tenantRegistry) all() []*tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenants := make([]*tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		tenants = append(tenants, t)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].id < tenants[j].id })
+
+	return tenants
+}
+
+// usage reports session, thought and call counts of every tenant
+func (r *tenantRegistry) usage() []TenantUsage {
+	usage := []TenantUsage{}
+	for _, t := range r.all() {
+		u := TenantUsage{
+			Tenant:   t.id,
+			Calls:    t.calls.Load(),
+			Rejected: t.rejected.Load(),
+		}
+		for _, id := range t.store.List() {
+			if history, ok := t.store.Get(id); ok {
+				u.Sessions++
+				u.Thoughts += len(history.Thoughts)
+			}
+		}
+		usage = append(usage, u)
+	}
+
+	return usage
+}
+
+// LoadTenantLimits reads per-tenant rate limits from a JSON object keyed by tenant ID:
+//
+//	{"acme": {"rate": 5, "burst": 10, "dailyQuota": 1000}}
+func LoadTenantLimits(path string) (map[string]RateLimitConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading tenant limits: %w", err)
+	}
+
+	var limits map[string]RateLimitConfig
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, fmt.Errorf("parsing tenant limits: %w", err)
+	}
+	for id := range limits {
+		if !validTenantID.MatchString(id) {
+			return nil, fmt.Errorf("tenant limits: invalid tenant ID %q", id)
+		}
+	}
+
+	return limits, nil
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx naming the tenant the caller asked
+// for, such as one taken from a request header
+func ContextWithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// TenantFromContext resolves the caller's tenant. A tenant asserted by the
+// authenticator wins over one set by ContextWithTenant, which would
+// otherwise let a caller pick any tenant.
+func TenantFromContext(ctx context.Context) (string, error) {
+	id := DefaultTenant
+	if p, ok := PrincipalFromContext(ctx); ok && p.Tenant != "" {
+		id = p.Tenant
+	} else if requested, ok := ctx.Value(tenantKey{}).(string); ok {
+		id = requested
+	}
+
+	if !validTenantID.MatchString(id) {
+		return "", fmt.Errorf("invalid tenant ID %q", id)
+	}
+
+	return id, nil
+}
+
+// TenantUsage reports session, thought and call counts of every tenant
+func (s *SequentialThinkingServer) TenantUsage() []TenantUsage {
+	return s.tenants.usage()
+}
+
+// Store returns the session store of a tenant, creating the tenant if
+// needed; it fails with ErrTooManyTenants once the cap is reached
+func (s *SequentialThinkingServer) Store(tenantID string) (SessionStore, error) {
+	t, err := s.tenants.get(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.store, nil
+}
+
+// DeleteSession removes one of the caller's sessions from the caller's
+// tenant and notes the deletion in the call log. Sessions of other subjects
+// are reported as ErrSessionNotFound.
+func (s *SequentialThinkingServer) DeleteSession(ctx context.Context, sessionID string) error {
+	if !OwnsSession(ctx, sessionID) {
+		return ErrSessionNotFound
+	}
+	tenantID, err := TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	t, err := s.tenants.get(tenantID)
+	if err != nil {
+		return err
+	}
+
+	if !s.begin() {
+		return errShuttingDown
+	}
+	defer s.inflight.Done()
+
+	if err := t.store.Delete(sessionID); err != nil {
+		return err
+	}
+	s.recordDeletion(ctx, t.id, sessionID)
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package thinking
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func thoughtCall(number int, next bool) mcp.CallToolRequest {
+	return mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "sequentialthinking",
+			Arguments: map[string]interface{}{
+				"thought":           "Thinking",
+				"nextThoughtNeeded": next,
+				"thoughtNumber":     float64(number),
+				"totalThoughts":     float64(3),
+			},
+		},
+	}
+}
+
+func TestTenantsDoNotShareSessions(t *testing.T) {
+	server := NewSequentialThinkingServer()
+	// Same subject in two tenants must still yield two separate histories
+	acme := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice", Tenant: "acme"})
+	globex := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice", Tenant: "globex"})
+
+	for _, n := range []int{1, 2} {
+		if _, err := server.CallTool(acme, thoughtCall(n, true)); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+	}
+
+	result, err := server.CallTool(globex, thoughtCall(1, false))
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; contains(text, "Summary") {
+		t.Errorf("Tenant globex sees thoughts of tenant acme: %s", text)
+	}
+
+	for id, want := range map[string]int{"acme": 2, "globex": 1} {
+		store := server.tenants.create(id).store
+		sessions := store.List()
+		if len(sessions) != 1 {
+			t.Fatalf("Expected 1 session in tenant %s, got %v", id, sessions)
+		}
+		history, _ := store.Get(sessions[0])
+		if len(history.Thoughts) != want {
+			t.Errorf("Expected %d thoughts in tenant %s, got %d", want, id, len(history.Thoughts))
+		}
+	}
+	if sessions := server.tenants.create(DefaultTenant).store.List(); len(sessions) != 0 {
+		t.Errorf("Expected no sessions in the default tenant, got %v", sessions)
+	}
+}
+
+func TestTenantRateLimitsAreIndependent(t *testing.T) {
+	server := NewSequentialThinkingServer(
+		WithRateLimit(RateLimitConfig{DailyQuota: 1}),
+		WithTenantLimits(map[string]RateLimitConfig{"acme": {DailyQuota: 2}}),
+	)
+	acme := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice", Tenant: "acme"})
+	globex := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice", Tenant: "globex"})
+
+	allowed := func(ctx context.Context) bool {
+		result, err := server.CallTool(ctx, thoughtCall(1, true))
+		if err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+		return !result.IsError
+	}
+
+	if !allowed(globex) || allowed(globex) {
+		t.Error("Expected globex to get the default quota of 1")
+	}
+	if !allowed(acme) || !allowed(acme) || allowed(acme) {
+		t.Error("Expected acme to get its override quota of 2, unaffected by globex")
+	}
+
+	usage := server.tenants.usage()
+	for _, u := range usage {
+		if u.Tenant == "acme" && (u.Calls != 3 || u.Rejected != 1) {
+			t.Errorf("Expected acme usage 3 calls/1 rejected, got %+v", u)
+		}
+	}
+}
+
+func TestRateLimitsFollowClientAcrossTenants(t *testing.T) {
+	server := NewSequentialThinkingServer(WithRateLimit(RateLimitConfig{DailyQuota: 1}))
+	alice := ContextWithPrincipal(context.Background(), &Principal{Subject: "alice"})
+
+	if result, _ := server.CallTool(ContextWithTenant(alice, "one"), thoughtCall(1, true)); result.IsError {
+		t.Fatal("First call was refused")
+	}
+	if result, _ := server.CallTool(ContextWithTenant(alice, "two"), thoughtCall(1, true)); !result.IsError {
+		t.Error("Expected switching tenants not to reset the client's quota")
+	}
+}
+
+func TestMaxTenants(t *testing.T) {
+	server := NewSequentialThinkingServer(
+		WithMaxTenants(1),
+		WithTenantLimits(map[string]RateLimitConfig{"acme": {DailyQuota: 10}}),
+	)
+	call := func(tenantID string) error {
+		_, err := server.CallTool(ContextWithTenant(context.Background(), tenantID), thoughtCall(1, true))
+		return err
+	}
+
+	if err := call("one"); err != nil {
+		t.Fatalf("First tenant was refused: %v", err)
+	}
+	if err := call("two"); !errors.Is(err, ErrTooManyTenants) {
+		t.Errorf("Expected ErrTooManyTenants beyond the cap, got %v", err)
+	}
+	for _, id := range []string{"one", "acme", DefaultTenant} {
+		if err := call(id); err != nil {
+			t.Errorf("Expected existing and declared tenant %s to be served, got %v", id, err)
+		}
+	}
+	if _, err := server.Store("three"); !errors.Is(err, ErrTooManyTenants) {
+		t.Errorf("Expected Store to respect the cap, got %v", err)
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	requested := ContextWithTenant(context.Background(), "acme")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    string
+		wantErr bool
+	}{
+		{name: "no tenant", ctx: context.Background(), want: DefaultTenant},
+		{name: "requested", ctx: requested, want: "acme"},
+		{name: "principal wins over request", ctx: ContextWithPrincipal(requested, &Principal{Subject: "bob", Tenant: "globex"}), want: "globex"},
+		{name: "principal without tenant falls back to request", ctx: ContextWithPrincipal(requested, &Principal{Subject: "bob"}), want: "acme"},
+		{name: "invalid request", ctx: ContextWithTenant(context.Background(), "../etc"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TenantFromContext(tt.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TenantFromContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("TenantFromContext() = '%s', want '%s'", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTenantLimits(t *testing.T) {
+	path := writeTempFile(t, "limits.json", `{"acme": {"rate": 5, "burst": 10, "dailyQuota": 1000}}`)
+	limits, err := LoadTenantLimits(path)
+	if err != nil {
+		t.Fatalf("LoadTenantLimits failed: %v", err)
+	}
+	if want := (RateLimitConfig{Rate: 5, Burst: 10, DailyQuota: 1000}); limits["acme"] != want {
+		t.Errorf("Expected %+v, got %+v", want, limits["acme"])
+	}
+
+	path = writeTempFile(t, "bad.json", `{"bad tenant": {"rate": 1}}`)
+	if _, err := LoadTenantLimits(path); err == nil {
+		t.Error("Expected an error for an invalid tenant ID")
+	}
+}
@@ -0,0 +1,43 @@
+package thinking
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates CallTool spans. It resolves through the global provider, so
+// it is a no-op until the program installs one.
+var tracer = otel.Tracer("github.com/ad/sequentialthinking")
+
+// thoughtAttributes describes a thought request as span attributes
+func thoughtAttributes(sessionID string, req *ThoughtRequest) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("sequentialthinking.session_id", sessionID),
+		attribute.Int("sequentialthinking.thought_number", req.ThoughtNumber),
+		attribute.Int("sequentialthinking.total_thoughts", req.TotalThoughts),
+		attribute.Bool("sequentialthinking.next_thought_needed", req.NextThoughtNeeded),
+		attribute.Bool("sequentialthinking.is_revision", req.IsRevision),
+	}
+	if req.IsRevision {
+		attrs = append(attrs, attribute.Int("sequentialthinking.revises_thought", req.RevisesThought))
+	}
+	if req.BranchID != "" {
+		attrs = append(attrs,
+			attribute.String("sequentialthinking.branch_id", req.BranchID),
+			attribute.Int("sequentialthinking.branch_from_thought", req.BranchFromThought),
+		)
+	}
+
+	return attrs
+}
+
+// endSpan records err on the span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,64 @@
+package thinking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCallToolSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+
+	server := NewSequentialThinkingServer()
+	_, err := server.CallTool(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "sequentialthinking",
+			Arguments: map[string]interface{}{
+				"thought":           "Try another route",
+				"nextThoughtNeeded": true,
+				"thoughtNumber":     float64(2),
+				"totalThoughts":     float64(3),
+				"branchId":          "alt",
+				"branchFromThought": float64(1),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans[0].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+
+	if got := attrs["sequentialthinking.thought_number"].AsInt64(); got != 2 {
+		t.Errorf("Expected thought_number 2, got %d", got)
+	}
+	if got := attrs["sequentialthinking.branch_id"].AsString(); got != "alt" {
+		t.Errorf("Expected branch_id 'alt', got '%s'", got)
+	}
+	if got := attrs["sequentialthinking.session_id"].AsString(); got == "" {
+		t.Error("Expected session_id attribute")
+	}
+	if got := attrs["sequentialthinking.outcome"].AsString(); got != OutcomeSuccess {
+		t.Errorf("Expected outcome '%s', got '%s'", OutcomeSuccess, got)
+	}
+}
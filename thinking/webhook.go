@@ -0,0 +1,340 @@
+package thinking
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers sent with every webhook delivery
+const (
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// Outcomes recorded for every webhook delivery
+const (
+	webhookDelivered = "delivered"
+	webhookFailed    = "failed"
+	webhookDropped   = "dropped"
+)
+
+// WebhookEventTypes are the event types a webhook can subscribe to
+var WebhookEventTypes = []string{EventSessionCreated, EventThoughtAppended, EventBranchCreated, EventSessionCompleted}
+
+// WebhookConfig configures outbound webhooks; zero values take the defaults
+// noted on each field
+type WebhookConfig struct {
+	// URLs receive a POST for every subscribed event
+	URLs []string
+	// Secret signs each delivery with HMAC-SHA256; empty disables signing
+	Secret []byte
+	// Events limits deliveries to these event types; empty means all
+	Events []string
+	// QueueSize is the number of undelivered events kept per URL (default 1000)
+	QueueSize int
+	// MaxAttempts is the number of tries per event before giving up (default 5)
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled on each further one (default 1s)
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries (default 1m)
+	MaxBackoff time.Duration
+	// Timeout bounds each delivery attempt (default 10s)
+	Timeout time.Duration
+	// UserAgent is sent with every delivery (default sequentialthinking-webhook)
+	UserAgent string
+}
+
+// Validate reports URLs and event types the dispatcher cannot use
+func (c WebhookConfig) Validate() error {
+	for _, raw := range c.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL %q: must be an absolute http or https URL", raw)
+		}
+	}
+	for _, typ := range c.Events {
+		if !slices.Contains(WebhookEventTypes, typ) {
+			return fmt.Errorf("unknown webhook event %q, use one of %s", typ, strings.Join(WebhookEventTypes, ", "))
+		}
+	}
+
+	return nil
+}
+
+// withDefaults fills in the zero values
+func (c WebhookConfig) withDefaults() WebhookConfig {
+	if c.QueueSize < 1 {
+		c.QueueSize = 1000
+	}
+	if c.MaxAttempts < 1 {
+		c.MaxAttempts = 5
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = time.Second
+	}
+	if c.MaxBackoff < c.Backoff {
+		c.MaxBackoff = max(time.Minute, c.Backoff)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = "sequentialthinking-webhook"
+	}
+
+	return c
+}
+
+// LoadWebhookSecret reads the signing secret, ignoring surrounding whitespace
+func LoadWebhookSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading webhook secret: %w", err)
+	}
+	secret := bytes.TrimSpace(data)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("webhook secret file %s is empty", path)
+	}
+
+	return secret, nil
+}
+
+// signWebhook returns the signature header value for a delivery. The
+// timestamp is signed along with the body so receivers can reject replays.
+func signWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEndpoint is one receiving URL with its own queue, so a slow or
+// failing receiver never delays the others
+type webhookEndpoint struct {
+	url   string
+	queue chan SessionEvent
+}
+
+// webhookDispatcher delivers session events to the configured URLs. Each URL
+// gets events in publication order; when its queue is full new events are
+// dropped rather than blocking tool calls.
+type webhookDispatcher struct {
+	cfg        WebhookConfig
+	events     map[string]bool
+	client     *http.Client
+	logger     *slog.Logger
+	deliveries func(outcome string)
+
+	endpoints   []*webhookEndpoint
+	unsubscribe func()
+	stop        chan struct{}
+	stopOnce    sync.Once
+	pumped      chan struct{}
+	workers     sync.WaitGroup
+
+	// abort cancels retries and requests still running when shutdown times out
+	abort  context.Context
+	cancel context.CancelFunc
+}
+
+// newWebhookDispatcher subscribes to bus and starts delivering.
+// deliveries is called with the outcome of every event per URL.
+func newWebhookDispatcher(cfg WebhookConfig, bus *eventBus, logger *slog.Logger, deliveries func(outcome string)) *webhookDispatcher {
+	cfg = cfg.withDefaults()
+	d := &webhookDispatcher{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		logger:     logger,
+		deliveries: deliveries,
+		stop:       make(chan struct{}),
+		pumped:     make(chan struct{}),
+	}
+	d.abort, d.cancel = context.WithCancel(context.Background())
+	if len(cfg.Events) > 0 {
+		d.events = make(map[string]bool)
+		for _, typ := range cfg.Events {
+			d.events[typ] = true
+		}
+	}
+
+	for _, u := range cfg.URLs {
+		endpoint := &webhookEndpoint{url: u, queue: make(chan SessionEvent, cfg.QueueSize)}
+		d.endpoints = append(d.endpoints, endpoint)
+		d.workers.Add(1)
+		go d.deliverAll(endpoint)
+	}
+
+	events, unsubscribe := bus.subscribe(cfg.QueueSize)
+	d.unsubscribe = unsubscribe
+	go d.pump(events)
+
+	return d
+}
+
+// pump copies subscribed events into every endpoint queue without blocking
+func (d *webhookDispatcher) pump(events <-chan SessionEvent) {
+	defer close(d.pumped)
+	defer func() {
+		for _, endpoint := range d.endpoints {
+			close(endpoint.queue)
+		}
+	}()
+
+	for {
+		select {
+		case event := <-events:
+			d.enqueue(event)
+		case <-d.stop:
+			// Keep what was published before unsubscribing
+			for {
+				select {
+				case event := <-events:
+					d.enqueue(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *webhookDispatcher) enqueue(event SessionEvent) {
+	if d.events != nil && !d.events[event.Type] {
+		return
+	}
+	for _, endpoint := range d.endpoints {
+		select {
+		case endpoint.queue <- event:
+		default:
+			d.deliveries(webhookDropped)
+			d.logger.Warn("webhook queue full, dropping event", "url", endpoint.url, "event", event.Type, "session", event.SessionID)
+		}
+	}
+}
+
+// deliverAll sends the endpoint's events one at a time until its queue closes
+func (d *webhookDispatcher) deliverAll(endpoint *webhookEndpoint) {
+	defer d.workers.Done()
+
+	for event := range endpoint.queue {
+		if err := d.deliver(endpoint.url, event); err != nil {
+			d.deliveries(webhookFailed)
+			d.logger.Error("webhook delivery failed", "url", endpoint.url, "event", event.Type, "session", event.SessionID, "error", err)
+			continue
+		}
+		d.deliveries(webhookDelivered)
+	}
+}
+
+// deliver POSTs one event, retrying transport errors, 408, 429 and 5xx
+// responses with exponential backoff. Every attempt carries the same delivery
+// ID so receivers can discard duplicates.
+func (d *webhookDispatcher) deliver(target string, event SessionEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	deliveryID := uuid.NewString()
+
+	delay := d.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.attempt(target, deliveryID, event.Type, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == d.cfg.MaxAttempts {
+			return fmt.Errorf("attempt %d: %w", attempt, err)
+		}
+
+		d.logger.Debug("retrying webhook delivery", "url", target, "event", event.Type, "attempt", attempt, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-d.abort.Done():
+			timer.Stop()
+			return fmt.Errorf("attempt %d: %w (shutting down)", attempt, err)
+		}
+		delay = min(2*delay, d.cfg.MaxBackoff)
+	}
+}
+
+// attempt makes one delivery request and reports whether a failure is worth retrying
+func (d *webhookDispatcher) attempt(target, deliveryID, eventType string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(d.abort, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", d.cfg.UserAgent)
+	req.Header.Set(webhookEventHeader, eventType)
+	req.Header.Set(webhookDeliveryHeader, deliveryID)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	if len(d.cfg.Secret) > 0 {
+		req.Header.Set(webhookSignatureHeader, signWebhook(d.cfg.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return d.abort.Err() == nil, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("receiver returned %s", resp.Status)
+	}
+}
+
+// close stops taking new events and waits for the queued ones to be
+// delivered. If ctx expires first, pending deliveries are abandoned and ctx's
+// error is returned. Calling close again waits for the same deliveries.
+func (d *webhookDispatcher) close(ctx context.Context) error {
+	d.stopOnce.Do(func() {
+		d.unsubscribe()
+		close(d.stop)
+	})
+	<-d.pumped
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return fmt.Errorf("delivering queued webhooks: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,235 @@
+package thinking
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// webhookDelivery is one request seen by a webhookReceiver
+type webhookDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver records deliveries and answers with the statuses in
+// respond, one per request, then 200
+type webhookReceiver struct {
+	mu         sync.Mutex
+	deliveries []webhookDelivery
+	respond    []int
+}
+
+func startWebhookReceiver(t *testing.T, respond ...int) (*webhookReceiver, *httptest.Server) {
+	t.Helper()
+
+	rec := &webhookReceiver{respond: respond}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.deliveries = append(rec.deliveries, webhookDelivery{header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(rec.respond) > 0 {
+			status, rec.respond = rec.respond[0], rec.respond[1:]
+		}
+		rec.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(ts.Close)
+
+	return rec, ts
+}
+
+func (r *webhookReceiver) received() []webhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]webhookDelivery(nil), r.deliveries...)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	rec, ts := startWebhookReceiver(t)
+	secret := []byte("s3cret")
+	thinker := NewSequentialThinkingServer(WithWebhooks(WebhookConfig{
+		URLs:   []string{ts.URL},
+		Secret: secret,
+		Events: []string{EventSessionCreated, EventBranchCreated, EventSessionCompleted},
+	}))
+
+	calls := []map[string]interface{}{
+		{"thought": "Start", "nextThoughtNeeded": true, "thoughtNumber": float64(1), "totalThoughts": float64(2)},
+		{"thought": "Alternative", "nextThoughtNeeded": true, "thoughtNumber": float64(2), "totalThoughts": float64(2), "branchId": "alt", "branchFromThought": float64(1)},
+		{"thought": "Done", "nextThoughtNeeded": false, "thoughtNumber": float64(2), "totalThoughts": float64(2)},
+	}
+	for _, args := range calls {
+		request := thoughtCall(1, true)
+		request.Params.Arguments = args
+		if _, err := thinker.CallTool(context.Background(), request); err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+	}
+
+	// Shutdown delivers everything still queued
+	if err := thinker.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	var types []string
+	for _, d := range rec.received() {
+		var event SessionEvent
+		if err := json.Unmarshal(d.body, &event); err != nil {
+			t.Fatalf("Delivery body is not an event: %v", err)
+		}
+		types = append(types, event.Type)
+
+		if got := d.header.Get(webhookEventHeader); got != event.Type {
+			t.Errorf("Expected %s header %s, got %s", webhookEventHeader, event.Type, got)
+		}
+		if d.header.Get(webhookDeliveryHeader) == "" {
+			t.Errorf("Missing %s header", webhookDeliveryHeader)
+		}
+		want := signWebhook(secret, d.header.Get(webhookTimestampHeader), d.body)
+		if got := d.header.Get(webhookSignatureHeader); got != want {
+			t.Errorf("Expected signature %s, got %s", want, got)
+		}
+	}
+
+	want := []string{EventSessionCreated, EventBranchCreated, EventSessionCompleted}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("Expected events %v in order, got %v", want, types)
+	}
+	if got := testutil.ToFloat64(thinker.metrics.webhookDeliveries.WithLabelValues(webhookDelivered)); got != 3 {
+		t.Errorf("Expected 3 delivered webhooks, got %v", got)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		respond  []int
+		attempts int
+		outcome  string
+	}{
+		{"recovers after server errors", []int{503, 429}, 3, webhookDelivered},
+		{"gives up after max attempts", []int{500, 500, 500, 500}, 3, webhookFailed},
+		{"does not retry client errors", []int{400}, 1, webhookFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, ts := startWebhookReceiver(t, tt.respond...)
+			bus := newEventBus()
+			outcomes := make(chan string, 1)
+			d := newWebhookDispatcher(WebhookConfig{
+				URLs:        []string{ts.URL},
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+			}, bus, slog.New(slog.DiscardHandler), func(outcome string) { outcomes <- outcome })
+
+			bus.publish(SessionEvent{Type: EventSessionCompleted, SessionID: "s1"})
+			if got := <-outcomes; got != tt.outcome {
+				t.Errorf("Expected outcome %s, got %s", tt.outcome, got)
+			}
+			if err := d.close(context.Background()); err != nil {
+				t.Fatalf("close failed: %v", err)
+			}
+
+			deliveries := rec.received()
+			if len(deliveries) != tt.attempts {
+				t.Fatalf("Expected %d attempts, got %d", tt.attempts, len(deliveries))
+			}
+			for _, delivery := range deliveries {
+				if got := delivery.header.Get(webhookDeliveryHeader); got != deliveries[0].header.Get(webhookDeliveryHeader) {
+					t.Errorf("Retries must reuse the delivery ID, got %s", got)
+				}
+				if delivery.header.Get(webhookSignatureHeader) != "" {
+					t.Errorf("Deliveries without a secret must not be signed")
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookQueueIsBounded(t *testing.T) {
+	release := make(chan struct{})
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+	}))
+	t.Cleanup(ts.Close)
+
+	bus := newEventBus()
+	var mu sync.Mutex
+	outcomes := map[string]int{}
+	d := newWebhookDispatcher(WebhookConfig{URLs: []string{ts.URL}, QueueSize: 2}, bus, slog.New(slog.DiscardHandler), func(outcome string) {
+		mu.Lock()
+		outcomes[outcome]++
+		mu.Unlock()
+	})
+
+	// The first event blocks in the receiver; two more fill the queue
+	bus.publish(SessionEvent{Type: EventThoughtAppended})
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 5; i++ {
+		bus.publish(SessionEvent{Type: EventThoughtAppended})
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(release)
+	if err := d.close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if outcomes[webhookDelivered] != 3 || outcomes[webhookDropped] != 3 {
+		t.Errorf("Expected 3 delivered and 3 dropped events, got %v", outcomes)
+	}
+}
+
+func TestWebhookShutdownTimeout(t *testing.T) {
+	_, ts := startWebhookReceiver(t, 503, 503, 503)
+	bus := newEventBus()
+	d := newWebhookDispatcher(WebhookConfig{
+		URLs:    []string{ts.URL},
+		Backoff: time.Hour,
+	}, bus, slog.New(slog.DiscardHandler), func(string) {})
+	bus.publish(SessionEvent{Type: EventSessionCreated})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.close(ctx); err == nil {
+		t.Error("Expected close to give up on a delivery stuck in backoff")
+	}
+}
+
+func TestWebhookConfigValidate(t *testing.T) {
+	valid := WebhookConfig{URLs: []string{"https://hooks.example.com/think"}, Events: []string{EventSessionCompleted}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+
+	for _, cfg := range []WebhookConfig{
+		{URLs: []string{"hooks.example.com/think"}},
+		{URLs: []string{"ftp://hooks.example.com"}},
+		{Events: []string{"session.deleted"}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg)
+		}
+	}
+}
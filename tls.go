@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// clientAuthModes maps -tls-client-auth values to the TLS verification policy
+var clientAuthModes = map[string]tls.ClientAuthType{
+	"none":            tls.NoClientCert,
+	"request":         tls.RequestClientCert,
+	"verify-if-given": tls.VerifyClientCertIfGiven,
+	"require":         tls.RequireAndVerifyClientCert,
+}
+
+// tlsOptions configures TLS termination on the HTTP and SSE listeners
+type tlsOptions struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	// clientAuth is a key of clientAuthModes; empty means "require" when a
+	// client CA is configured and "none" otherwise
+	clientAuth string
+}
+
+// newTLSConfig builds a server TLS config whose certificate is reloaded
+// whenever the certificate or key file changes on disk
+func newTLSConfig(opts tlsOptions) (*tls.Config, error) {
+	reloader, err := newCertReloader(opts.certFile, opts.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	mode := opts.clientAuth
+	if mode == "" {
+		mode = "none"
+		if opts.clientCAFile != "" {
+			mode = "require"
+		}
+	}
+	clientAuth, ok := clientAuthModes[mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown TLS client auth mode: %s", mode)
+	}
+	cfg.ClientAuth = clientAuth
+
+	if opts.clientCAFile != "" {
+		pool, err := loadCertPool(opts.clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+	} else if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+		return nil, fmt.Errorf("TLS client auth mode %s requires a client CA file", mode)
+	}
+
+	return cfg, nil
+}
+
+// loadCertPool reads PEM encoded CA certificates from path
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("client CA file %s contains no PEM certificates", path)
+	}
+
+	return pool, nil
+}
+
+// certReloader serves a key pair from disk and reloads it when either file's
+// modification time changes. A failed reload keeps serving the previous pair,
+// so a half-written rotation never takes the listener down.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// newCertReloader loads the initial key pair, failing if it is unusable
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both a TLS certificate and key are required")
+	}
+
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.changed() {
+		if err := r.reload(); err != nil {
+			slog.Warn("TLS certificate reload failed, keeping previous certificate", "error", err)
+		} else {
+			slog.Info("TLS certificate reloaded", "cert", r.certFile)
+		}
+	}
+
+	return r.cert, nil
+}
+
+// changed reports whether either file differs from the loaded pair
+func (r *certReloader) changed() bool {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false
+	}
+
+	return !certInfo.ModTime().Equal(r.certMod) || !keyInfo.ModTime().Equal(r.keyMod)
+}
+
+// reload reads the key pair. The modification times are recorded even when
+// loading fails, so a broken pair is retried only after the files change again.
+func (r *certReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS key: %w", err)
+	}
+	r.certMod = certInfo.ModTime()
+	r.keyMod = keyInfo.ModTime()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	r.cert = &cert
+
+	return nil
+}
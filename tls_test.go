@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// testCert is a generated certificate with its PEM encodings
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert creates a certificate for commonName, self-signed when parent is nil
+func newTestCert(t *testing.T, commonName string, isCA bool, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{"localhost"},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeCert writes c to certFile and keyFile, stamping both with mtime
+func writeCert(t *testing.T, c *testCert, certFile, keyFile string, mtime time.Time) {
+	t.Helper()
+
+	for path, data := range map[string][]byte{certFile: c.certPEM, keyFile: c.keyPEM} {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Failed to set mtime on %s: %v", path, err)
+		}
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	base := time.Now().Add(-time.Minute)
+
+	first := newTestCert(t, "first", false, nil)
+	writeCert(t, first, certFile, keyFile, base)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+
+	servedCN := func() string {
+		cert, err := reloader.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("GetCertificate failed: %v", err)
+		}
+		leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+		return leaf.Subject.CommonName
+	}
+
+	if cn := servedCN(); cn != "first" {
+		t.Fatalf("Expected 'first', got '%s'", cn)
+	}
+
+	second := newTestCert(t, "second", false, nil)
+	writeCert(t, second, certFile, keyFile, base.Add(time.Second))
+	if cn := servedCN(); cn != "second" {
+		t.Errorf("Expected reloaded 'second', got '%s'", cn)
+	}
+
+	// A broken rotation keeps the last good certificate
+	if err := os.WriteFile(certFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chtimes(certFile, base.Add(2*time.Second), base.Add(2*time.Second))
+	if cn := servedCN(); cn != "second" {
+		t.Errorf("Expected previous 'second' after failed reload, got '%s'", cn)
+	}
+}
+
+func TestNewTLSConfigClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, newTestCert(t, "server", false, nil), certFile, keyFile, time.Now())
+	caFile := writeTempFile(t, "ca.pem", string(newTestCert(t, "ca", true, nil).certPEM))
+
+	tests := []struct {
+		name       string
+		opts       tlsOptions
+		wantPolicy tls.ClientAuthType
+		wantErr    bool
+	}{
+		{name: "no client auth", opts: tlsOptions{}, wantPolicy: tls.NoClientCert},
+		{name: "CA defaults to require", opts: tlsOptions{clientCAFile: caFile}, wantPolicy: tls.RequireAndVerifyClientCert},
+		{name: "optional verification", opts: tlsOptions{clientCAFile: caFile, clientAuth: "verify-if-given"}, wantPolicy: tls.VerifyClientCertIfGiven},
+		{name: "verification without CA", opts: tlsOptions{clientAuth: "require"}, wantErr: true},
+		{name: "unknown mode", opts: tlsOptions{clientAuth: "sometimes"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.certFile, tt.opts.keyFile = certFile, keyFile
+			cfg, err := newTLSConfig(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.ClientAuth != tt.wantPolicy {
+				t.Errorf("Expected client auth %v, got %v", tt.wantPolicy, cfg.ClientAuth)
+			}
+		})
+	}
+}
+
+func TestMutualTLSHandshake(t *testing.T) {
+	ca := newTestCert(t, "test-ca", true, nil)
+	serverCert := newTestCert(t, "localhost", false, ca)
+	clientCert := newTestCert(t, "dave", false, ca)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, serverCert, certFile, keyFile, time.Now())
+
+	cfg, err := newTLSConfig(tlsOptions{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: writeTempFile(t, "ca.pem", string(ca.certPEM)),
+	})
+	if err != nil {
+		t.Fatalf("newTLSConfig failed: %v", err)
+	}
+
+	ts := httptest.NewUnstartedServer(requireAuth(mtlsAuthenticator{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := thinking.PrincipalFromContext(r.Context())
+		_, _ = w.Write([]byte(p.Subject))
+	})))
+	ts.TLS = cfg
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientPair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load client key pair: %v", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientPair},
+		ServerName:   "localhost",
+	}}}
+
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("mTLS request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body := make([]byte, 16)
+	n, _ := resp.Body.Read(body)
+	if resp.StatusCode != http.StatusOK || string(body[:n]) != "dave" {
+		t.Errorf("Expected 200 for 'dave', got %d '%s'", resp.StatusCode, body[:n])
+	}
+
+	// Without a client certificate the handshake is refused
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost"}}}
+	if resp, err := anonymous.Get(ts.URL); err == nil {
+		resp.Body.Close()
+		t.Error("Expected handshake failure without a client certificate")
+	}
+}
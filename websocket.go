@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// websocketOptions tunes the WebSocket transport
+type websocketOptions struct {
+	// maxMessageSize is the largest client message accepted, in bytes;
+	// larger messages close the connection with status 1009
+	maxMessageSize int64
+	// pingInterval is how often idle connections are pinged; a client that
+	// does not answer within the same interval is disconnected. Zero
+	// disables keepalive.
+	pingInterval time.Duration
+	// allowedOrigins lists the browser origins, besides the server's own,
+	// allowed to connect; "*" allows any
+	allowedOrigins []string
+}
+
+// websocketSession is the MCP client session of one WebSocket connection
+type websocketSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+}
+
+func (s *websocketSession) SessionID() string { return s.id }
+
+func (s *websocketSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+func (s *websocketSession) Initialize() { s.initialized.Store(true) }
+
+func (s *websocketSession) Initialized() bool { return s.initialized.Load() }
+
+// websocketServer speaks MCP JSON-RPC over WebSocket text frames, one
+// message per frame, against a shared MCPServer
+type websocketServer struct {
+	mcpServer   *server.MCPServer
+	opts        websocketOptions
+	contextFunc func(context.Context, *http.Request) context.Context
+	logger      *slog.Logger
+
+	mu     sync.Mutex
+	conns  map[*websocket.Conn]struct{}
+	closed bool
+}
+
+// newWebsocketServer creates the handler; contextFunc adds per-request data
+// to the context of every tool call made over the connection
+func newWebsocketServer(mcpServer *server.MCPServer, opts websocketOptions, contextFunc func(context.Context, *http.Request) context.Context) *websocketServer {
+	return &websocketServer{
+		mcpServer:   mcpServer,
+		opts:        opts,
+		contextFunc: contextFunc,
+		logger:      slog.Default(),
+		conns:       make(map[*websocket.Conn]struct{}),
+	}
+}
+
+// ServeHTTP upgrades the request and serves MCP until either side closes
+func (s *websocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		Subprotocols:       []string{"mcp"},
+		OriginPatterns:     s.opts.allowedOrigins,
+		InsecureSkipVerify: slices.Contains(s.opts.allowedOrigins, "*"),
+	})
+	if err != nil {
+		// Accept has already written the error response
+		return
+	}
+	if !s.track(conn) {
+		conn.Close(websocket.StatusGoingAway, "server shutting down")
+		return
+	}
+	defer s.untrack(conn)
+	conn.SetReadLimit(s.opts.maxMessageSize)
+
+	session := &websocketSession{
+		id:            uuid.NewString(),
+		notifications: make(chan mcp.JSONRPCNotification, 100),
+	}
+
+	ctx, cancel := context.WithCancel(s.contextFunc(r.Context(), r))
+	defer cancel()
+	ctx = s.mcpServer.WithContext(ctx, session)
+	if err := s.mcpServer.RegisterSession(ctx, session); err != nil {
+		conn.Close(websocket.StatusInternalError, "session registration failed")
+		return
+	}
+	defer s.mcpServer.UnregisterSession(ctx, session.id)
+
+	go s.forwardNotifications(ctx, conn, session)
+	if s.opts.pingInterval > 0 {
+		go s.keepAlive(ctx, conn)
+	}
+
+	for {
+		typ, data, err := conn.Read(ctx)
+		if err != nil {
+			status := websocket.CloseStatus(err)
+			if status != websocket.StatusNormalClosure && status != websocket.StatusGoingAway && ctx.Err() == nil {
+				s.logger.Debug("websocket connection closed", "session", session.id, "error", err)
+			}
+			conn.CloseNow()
+			return
+		}
+		if typ != websocket.MessageText {
+			conn.Close(websocket.StatusUnsupportedData, "expected text frames")
+			return
+		}
+
+		// HandleMessage answers malformed JSON with a parse error itself
+		response := s.mcpServer.HandleMessage(ctx, data)
+
+		// Notifications from the client have no response
+		if response != nil {
+			if err := writeWebsocketMessage(ctx, conn, response); err != nil {
+				conn.CloseNow()
+				return
+			}
+		}
+	}
+}
+
+// forwardNotifications sends server notifications to the client
+func (s *websocketServer) forwardNotifications(ctx context.Context, conn *websocket.Conn, session *websocketSession) {
+	for {
+		select {
+		case notification := <-session.notifications:
+			if err := writeWebsocketMessage(ctx, conn, notification); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// keepAlive pings the client every pingInterval and drops the connection
+// when a pong does not arrive in time
+func (s *websocketServer) keepAlive(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(s.opts.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, s.opts.pingInterval)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Debug("websocket client missed ping", "error", err)
+				}
+				conn.CloseNow()
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// writeWebsocketMessage sends msg as one JSON text frame
+func writeWebsocketMessage(ctx context.Context, conn *websocket.Conn, msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return conn.Write(ctx, websocket.MessageText, data)
+}
+
+// track registers an open connection; it refuses once Shutdown has begun
+func (s *websocketServer) track(conn *websocket.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+
+	return true
+}
+
+func (s *websocketServer) untrack(conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, conn)
+}
+
+// Shutdown closes every connection with status 1001. Hijacked WebSocket
+// connections are invisible to http.Server.Shutdown, so they must be closed here.
+func (s *websocketServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	conns := make([]*websocket.Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn.Close(websocket.StatusGoingAway, "server shutting down")
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		// Give up on the close handshake of clients that do not answer
+		for _, conn := range conns {
+			conn.CloseNow()
+		}
+		return ctx.Err()
+	}
+}
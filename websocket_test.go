@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/ad/sequentialthinking/thinking"
+)
+
+// startWebsocketServer serves the WebSocket transport of a fresh thinker
+func startWebsocketServer(t *testing.T, cfg httpConfig) (*thinking.SequentialThinkingServer, string) {
+	t.Helper()
+
+	mcpServer, thinker := newTestMCPServer()
+	mux := http.NewServeMux()
+	shutdown := mountTransports(mux, mcpServer, &http.Server{}, cfg, []string{"websocket"})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		shutdown(context.Background())
+		ts.Close()
+	})
+
+	return thinker, "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+}
+
+// rpc sends one JSON-RPC request and decodes the response
+func rpc(ctx context.Context, t *testing.T, conn *websocket.Conn, id int, method string, params any) map[string]any {
+	t.Helper()
+
+	request, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if err := conn.Write(ctx, websocket.MessageText, request); err != nil {
+		t.Fatalf("Writing %s failed: %v", method, err)
+	}
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("Reading %s response failed: %v", method, err)
+	}
+
+	var response map[string]any
+	if err := json.Unmarshal(data, &response); err != nil {
+		t.Fatalf("Decoding %s response failed: %v", method, err)
+	}
+	if response["error"] != nil {
+		t.Fatalf("%s returned an error: %v", method, response["error"])
+	}
+
+	return response
+}
+
+func TestWebsocketTransport(t *testing.T) {
+	thinker, url := startWebsocketServer(t, httpConfig{websocket: websocketOptions{maxMessageSize: 1 << 20, pingInterval: 50 * time.Millisecond}})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{Subprotocols: []string{"mcp"}})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.CloseNow()
+
+	rpc(ctx, t, conn, 1, "initialize", map[string]any{
+		"protocolVersion": "2025-03-26",
+		"clientInfo":      map[string]any{"name": "test", "version": "1"},
+	})
+	response := rpc(ctx, t, conn, 2, "tools/call", map[string]any{
+		"name": "sequentialthinking",
+		"arguments": map[string]any{
+			"thought":           "Thinking over a WebSocket",
+			"nextThoughtNeeded": false,
+			"thoughtNumber":     1,
+			"totalThoughts":     1,
+		},
+	})
+	if result := response["result"].(map[string]any); result["isError"] == true {
+		t.Fatalf("Tool call failed: %v", result)
+	}
+	if sessions := defaultStore(t, thinker).List(); len(sessions) != 1 {
+		t.Errorf("Expected 1 stored session, got %v", sessions)
+	}
+
+	// Pings are answered while the client reads, so the connection survives
+	// several keepalive intervals
+	pongs := conn.CloseRead(ctx)
+	select {
+	case <-pongs.Done():
+		t.Fatal("Connection closed while answering pings")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWebsocketMessageTooBig(t *testing.T) {
+	_, url := startWebsocketServer(t, httpConfig{websocket: websocketOptions{maxMessageSize: 64}})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.CloseNow()
+
+	if err := conn.Write(ctx, websocket.MessageText, []byte(strings.Repeat("x", 128))); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	_, _, err = conn.Read(ctx)
+	if status := websocket.CloseStatus(err); status != websocket.StatusMessageTooBig {
+		t.Errorf("Expected close status %d, got %d (%v)", websocket.StatusMessageTooBig, status, err)
+	}
+}
+
+func TestWebsocketDropsUnresponsiveClient(t *testing.T) {
+	_, url := startWebsocketServer(t, httpConfig{websocket: websocketOptions{maxMessageSize: 1 << 20, pingInterval: 20 * time.Millisecond}})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.CloseNow()
+
+	// Not reading means pings go unanswered
+	time.Sleep(200 * time.Millisecond)
+
+	if _, _, err := conn.Read(ctx); err == nil {
+		t.Fatal("Expected the server to drop a client that ignores pings")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Connection was not dropped before the test deadline")
+	}
+}
+
+// Cross-origin WebSocket handshakes are checked against the CORS origins
+func TestWebsocketOrigins(t *testing.T) {
+	dial := func(url, origin string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{HTTPHeader: http.Header{"Origin": {origin}}})
+		if err == nil {
+			conn.CloseNow()
+		}
+		return err
+	}
+
+	_, url := startWebsocketServer(t, httpConfig{websocket: websocketOptions{maxMessageSize: 1 << 20}})
+	if err := dial(url, "https://app.example.com"); err == nil {
+		t.Error("Expected cross-origin connections to be refused by default")
+	}
+
+	_, url = startWebsocketServer(t, httpConfig{
+		websocket: websocketOptions{maxMessageSize: 1 << 20},
+		cors:      corsConfig{allowedOrigins: []string{"https://app.example.com"}},
+	})
+	if err := dial(url, "https://app.example.com"); err != nil {
+		t.Errorf("Expected an allowed origin to connect, got %v", err)
+	}
+	if err := dial(url, "https://evil.example.com"); err == nil {
+		t.Error("Expected other origins to be refused")
+	}
+}